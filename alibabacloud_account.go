@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -14,6 +16,8 @@ import (
 // AlibabaCloudAccount holds the AlibabaCloud account name and roles
 type AlibabaCloudAccount struct {
 	Name  string
+	ID    string
+	Alias string
 	Roles []*RamRole
 }
 
@@ -63,6 +67,8 @@ func ExtractAlibabaCloudAccounts(data []byte) ([]*AlibabaCloudAccount, error) {
 		for accountId, accountRoleList := range roleList.RoleInfoList {
 			account := new(AlibabaCloudAccount)
 			account.Name = fmt.Sprintf("%s(%s)", roleList.AccountAliasList[accountId], accountId)
+			account.ID = accountId
+			account.Alias = roleList.AccountAliasList[accountId]
 			for _, roleInfo := range accountRoleList {
 				role := new(RamRole)
 				role.Name = roleInfo.RoleName
@@ -78,6 +84,60 @@ func ExtractAlibabaCloudAccounts(data []byte) ([]*AlibabaCloudAccount, error) {
 	return nil, errors.New("cannot find any roles")
 }
 
+// BuildAlibabaCloudAccounts group the roles by the account in their ARN, without contacting AlibabaCloud, the
+// account names are taken from the supplied aliases and fall back to the account id
+func BuildAlibabaCloudAccounts(ramRoles []*RamRole, aliases map[string]string) []*AlibabaCloudAccount {
+	accounts := []*AlibabaCloudAccount{}
+	accountsByID := make(map[string]*AlibabaCloudAccount)
+
+	for _, ramRole := range ramRoles {
+		accountID := ramRole.AccountID()
+
+		account, ok := accountsByID[accountID]
+		if !ok {
+			account = &AlibabaCloudAccount{ID: accountID, Alias: aliases[accountID]}
+			account.Name = accountName(account.Alias, accountID)
+			accountsByID[accountID] = account
+			accounts = append(accounts, account)
+		}
+
+		if ramRole.Name == "" {
+			ramRole.Name = ramRole.RoleName()
+		}
+		account.Roles = append(account.Roles, ramRole)
+	}
+
+	sort.SliceStable(accounts, func(i, j int) bool {
+		return accounts[i].ID < accounts[j].ID
+	})
+
+	return accounts
+}
+
+// EnrichAlibabaCloudAccounts fill the aliases missing from the accounts with the ones listed on the role selection page
+func EnrichAlibabaCloudAccounts(accounts []*AlibabaCloudAccount, pageAccounts []*AlibabaCloudAccount) {
+	aliases := make(map[string]string)
+	for _, pageAccount := range pageAccounts {
+		aliases[pageAccount.ID] = pageAccount.Alias
+	}
+
+	for _, account := range accounts {
+		if account.Alias != "" || aliases[account.ID] == "" {
+			continue
+		}
+		account.Alias = aliases[account.ID]
+		account.Name = accountName(account.Alias, account.ID)
+	}
+}
+
+func accountName(alias, accountID string) string {
+	if alias == "" {
+		return accountID
+	}
+
+	return fmt.Sprintf("%s(%s)", alias, accountID)
+}
+
 // AssignPrincipals assign principal from roles
 func AssignPrincipals(ramRoles []*RamRole, alibabacloudAccounts []*AlibabaCloudAccount) {
 
@@ -104,3 +164,80 @@ func LocateRole(ramRoles []*RamRole, roleName string) (*RamRole, error) {
 
 	return nil, fmt.Errorf("supplied `RoleArn` not found in saml assertion: %s", roleName)
 }
+
+// MatchRoles find the roles matching the supplied selector, which is either an alias from the
+// configuration file, a regular expression wrapped in slashes such as /admin$/, a glob such as
+// *:role/readonly or the full role ARN
+func MatchRoles(ramRoles []*RamRole, selector string, aliases map[string]string) ([]*RamRole, error) {
+	if roleARN, ok := aliases[selector]; ok {
+		selector = roleARN
+	}
+
+	match, err := roleMatcher(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []*RamRole{}
+	for _, ramRole := range ramRoles {
+		if match(ramRole.RoleARN) {
+			matched = append(matched, ramRole)
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("supplied `RoleArn` not found in saml assertion: %s", selector)
+	}
+
+	return matched, nil
+}
+
+func roleMatcher(selector string) (func(string) bool, error) {
+	if len(selector) > 2 && strings.HasPrefix(selector, "/") && strings.HasSuffix(selector, "/") {
+		re, err := regexp.Compile(selector[1 : len(selector)-1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid role regular expression: %s", selector)
+		}
+		return re.MatchString, nil
+	}
+
+	if strings.ContainsAny(selector, "*?") {
+		// unlike path.Match the wildcards also match the / in role/<name>
+		pattern := regexp.QuoteMeta(selector)
+		pattern = strings.Replace(pattern, `\*`, ".*", -1)
+		pattern = strings.Replace(pattern, `\?`, ".", -1)
+		re := regexp.MustCompile("^" + pattern + "$")
+		return re.MatchString, nil
+	}
+
+	return func(roleARN string) bool {
+		return roleARN == selector
+	}, nil
+}
+
+// FilterAlibabaCloudAccounts keep only the supplied roles in the accounts, dropping the accounts left without roles
+func FilterAlibabaCloudAccounts(accounts []*AlibabaCloudAccount, ramRoles []*RamRole) []*AlibabaCloudAccount {
+	roleARNs := make(map[string]bool)
+	for _, ramRole := range ramRoles {
+		roleARNs[ramRole.RoleARN] = true
+	}
+
+	filtered := []*AlibabaCloudAccount{}
+	for _, account := range accounts {
+		roles := []*RamRole{}
+		for _, ramRole := range account.Roles {
+			if roleARNs[ramRole.RoleARN] {
+				roles = append(roles, ramRole)
+			}
+		}
+		if len(roles) == 0 {
+			continue
+		}
+
+		filteredAccount := *account
+		filteredAccount.Roles = roles
+		filtered = append(filtered, &filteredAccount)
+	}
+
+	return filtered
+}
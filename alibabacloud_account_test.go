@@ -17,6 +17,8 @@ func TestExtractAlibabaCloudAccounts(t *testing.T) {
 
 	account := accounts[0]
 	assert.Equal(t, account.Name, "000000000001(000000000001)")
+	assert.Equal(t, account.ID, "000000000001")
+	assert.Equal(t, account.Alias, "000000000001")
 
 	assert.Len(t, account.Roles, 2)
 	role := account.Roles[0]
@@ -28,6 +30,8 @@ func TestExtractAlibabaCloudAccounts(t *testing.T) {
 
 	account = accounts[1]
 	assert.Equal(t, account.Name, "sharedservices(000000000002)")
+	assert.Equal(t, account.ID, "000000000002")
+	assert.Equal(t, account.Alias, "sharedservices")
 
 	assert.Len(t, account.Roles, 2)
 	role = account.Roles[0]
@@ -79,3 +83,112 @@ func TestLocateRole(t *testing.T) {
 
 	assert.Equal(t, "acs:ram::000000000001:role/Development", role.RoleARN)
 }
+
+func TestBuildAlibabaCloudAccounts(t *testing.T) {
+	ramRoles, err := ParseRamRoles([]string{
+		"acs:ram::000000000002:role/Development,acs:ram::000000000002:saml-provider/example-idp",
+		"acs:ram::000000000001:role/Production,acs:ram::000000000001:saml-provider/example-idp",
+		"acs:ram::000000000002:role/Production,acs:ram::000000000002:saml-provider/example-idp",
+	})
+	assert.Nil(t, err)
+
+	accounts := BuildAlibabaCloudAccounts(ramRoles, map[string]string{"000000000002": "sharedservices"})
+	assert.Len(t, accounts, 2)
+
+	account := accounts[0]
+	assert.Equal(t, "000000000001", account.ID)
+	assert.Equal(t, "", account.Alias)
+	assert.Equal(t, "000000000001", account.Name)
+	assert.Len(t, account.Roles, 1)
+	assert.Equal(t, "Production", account.Roles[0].Name)
+	assert.Equal(t, "acs:ram::000000000001:saml-provider/example-idp", account.Roles[0].PrincipalARN)
+
+	account = accounts[1]
+	assert.Equal(t, "000000000002", account.ID)
+	assert.Equal(t, "sharedservices", account.Alias)
+	assert.Equal(t, "sharedservices(000000000002)", account.Name)
+	assert.Len(t, account.Roles, 2)
+	assert.Equal(t, "Development", account.Roles[0].Name)
+	assert.Equal(t, "Production", account.Roles[1].Name)
+}
+
+func TestEnrichAlibabaCloudAccounts(t *testing.T) {
+	accounts := []*AlibabaCloudAccount{
+		{ID: "000000000001", Name: "000000000001"},
+		{ID: "000000000002", Alias: "shared", Name: "shared(000000000002)"},
+	}
+
+	EnrichAlibabaCloudAccounts(accounts, []*AlibabaCloudAccount{
+		{ID: "000000000001", Alias: "development"},
+		{ID: "000000000002", Alias: "sharedservices"},
+	})
+
+	assert.Equal(t, "development", accounts[0].Alias)
+	assert.Equal(t, "development(000000000001)", accounts[0].Name)
+	// local aliases take precedence over the role selection page
+	assert.Equal(t, "shared", accounts[1].Alias)
+	assert.Equal(t, "shared(000000000002)", accounts[1].Name)
+}
+
+func TestMatchRoles(t *testing.T) {
+	ramRoles, err := ParseRamRoles([]string{
+		"acs:ram::000000000001:role/admin,acs:ram::000000000001:saml-provider/example-idp",
+		"acs:ram::000000000001:role/readonly,acs:ram::000000000001:saml-provider/example-idp",
+		"acs:ram::000000000002:role/readonly,acs:ram::000000000002:saml-provider/example-idp",
+	})
+	assert.Nil(t, err)
+
+	aliases := map[string]string{
+		"prod-admin": "acs:ram::000000000001:role/admin",
+		"readonly":   "*:role/readonly",
+	}
+
+	tests := []struct {
+		selector string
+		expected []string
+	}{
+		{"acs:ram::000000000001:role/admin", []string{"acs:ram::000000000001:role/admin"}},
+		{"prod-admin", []string{"acs:ram::000000000001:role/admin"}},
+		{"*:role/readonly", []string{"acs:ram::000000000001:role/readonly", "acs:ram::000000000002:role/readonly"}},
+		{"readonly", []string{"acs:ram::000000000001:role/readonly", "acs:ram::000000000002:role/readonly"}},
+		{"acs:ram::000000000001:*", []string{"acs:ram::000000000001:role/admin", "acs:ram::000000000001:role/readonly"}},
+		{"/00000000000[2].*only$/", []string{"acs:ram::000000000002:role/readonly"}},
+	}
+
+	for _, tt := range tests {
+		matched, err := MatchRoles(ramRoles, tt.selector, aliases)
+		assert.Nil(t, err, tt.selector)
+
+		roleARNs := []string{}
+		for _, ramRole := range matched {
+			roleARNs = append(roleARNs, ramRole.RoleARN)
+		}
+		assert.Equal(t, tt.expected, roleARNs, tt.selector)
+	}
+
+	_, err = MatchRoles(ramRoles, "acs:ram::000000000003:role/admin", aliases)
+	assert.Error(t, err)
+
+	_, err = MatchRoles(ramRoles, "/(/", aliases)
+	assert.Error(t, err)
+}
+
+func TestFilterAlibabaCloudAccounts(t *testing.T) {
+	ramRoles, err := ParseRamRoles([]string{
+		"acs:ram::000000000001:role/admin,acs:ram::000000000001:saml-provider/example-idp",
+		"acs:ram::000000000001:role/readonly,acs:ram::000000000001:saml-provider/example-idp",
+		"acs:ram::000000000002:role/admin,acs:ram::000000000002:saml-provider/example-idp",
+	})
+	assert.Nil(t, err)
+
+	accounts := BuildAlibabaCloudAccounts(ramRoles, nil)
+
+	filtered := FilterAlibabaCloudAccounts(accounts, ramRoles[1:2])
+	assert.Len(t, filtered, 1)
+	assert.Equal(t, "000000000001", filtered[0].ID)
+	assert.Len(t, filtered[0].Roles, 1)
+	assert.Equal(t, "acs:ram::000000000001:role/readonly", filtered[0].Roles[0].RoleARN)
+
+	// the accounts themselves are left untouched
+	assert.Len(t, accounts[0].Roles, 2)
+}
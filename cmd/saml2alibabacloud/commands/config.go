@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/pkg/errors"
+)
+
+// ConfigList print the names of the configured idp accounts
+func ConfigList(configFlags *flags.ConfigFlags) error {
+	cfgm, err := cfg.NewConfigManager(configFlags.CommonFlags.ConfigFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load configuration")
+	}
+
+	names, err := cfgm.IDPAccountNames()
+	if err != nil {
+		return errors.Wrap(err, "failed to load idp accounts")
+	}
+
+	for _, name := range names {
+		fmt.Println(name)
+	}
+
+	return nil
+}
+
+// ConfigShow print the settings of the idp account as they are in the configuration file
+func ConfigShow(configFlags *flags.ConfigFlags) error {
+	cfgm, err := cfg.NewConfigManager(configFlags.CommonFlags.ConfigFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load configuration")
+	}
+
+	values, err := cfgm.IDPAccountSection(configFlags.Account)
+	if err != nil {
+		return errors.Wrap(err, "failed to load idp account")
+	}
+
+	return writeSection(os.Stdout, configFlags.Account, values)
+}
+
+func writeSection(w io.Writer, name string, values [][2]string) error {
+	if _, err := fmt.Fprintf(w, "[%s]\n", name); err != nil {
+		return err
+	}
+	for _, kv := range values {
+		if _, err := fmt.Fprintf(w, "%s = %s\n", kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConfigSet set a key of the idp account
+func ConfigSet(configFlags *flags.ConfigFlags) error {
+	cfgm, err := cfg.NewConfigManager(configFlags.CommonFlags.ConfigFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load configuration")
+	}
+
+	err = cfgm.SetIDPAccountValue(configFlags.Account, configFlags.Key, configFlags.Value)
+	if err != nil {
+		return errors.Wrap(err, "failed to update idp account")
+	}
+
+	log.Printf("Set %s of idp account %s", configFlags.Key, configFlags.Account)
+	return nil
+}
+
+// ConfigDelete remove the idp account
+func ConfigDelete(configFlags *flags.ConfigFlags) error {
+	cfgm, err := cfg.NewConfigManager(configFlags.CommonFlags.ConfigFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load configuration")
+	}
+
+	err = cfgm.DeleteIDPAccount(configFlags.Account)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete idp account")
+	}
+
+	log.Printf("Deleted idp account %s", configFlags.Account)
+	return nil
+}
+
+// ConfigRename rename the idp account
+func ConfigRename(configFlags *flags.ConfigFlags) error {
+	cfgm, err := cfg.NewConfigManager(configFlags.CommonFlags.ConfigFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load configuration")
+	}
+
+	err = cfgm.RenameIDPAccount(configFlags.Account, configFlags.NewName)
+	if err != nil {
+		return errors.Wrap(err, "failed to rename idp account")
+	}
+
+	log.Printf("Renamed idp account %s to %s", configFlags.Account, configFlags.NewName)
+	return nil
+}
+
+// ConfigCopy copy the idp account to a new one
+func ConfigCopy(configFlags *flags.ConfigFlags) error {
+	cfgm, err := cfg.NewConfigManager(configFlags.CommonFlags.ConfigFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load configuration")
+	}
+
+	err = cfgm.CopyIDPAccount(configFlags.Account, configFlags.NewName)
+	if err != nil {
+		return errors.Wrap(err, "failed to copy idp account")
+	}
+
+	log.Printf("Copied idp account %s to %s", configFlags.Account, configFlags.NewName)
+	return nil
+}
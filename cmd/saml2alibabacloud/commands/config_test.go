@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "saml2alibabacloud")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, ".saml2alibabacloud")
+	err = ioutil.WriteFile(configFile, []byte(`[work]
+url      = https://id.example.com
+provider = Okta
+mfa      = Auto
+`), 0600)
+	require.Nil(t, err)
+
+	configFlags := func(account, key, value, newName string) *flags.ConfigFlags {
+		return &flags.ConfigFlags{
+			CommonFlags: &flags.CommonFlags{ConfigFile: configFile},
+			Account:     account,
+			Key:         key,
+			Value:       value,
+			NewName:     newName,
+		}
+	}
+
+	require.Nil(t, ConfigSet(configFlags("work", "timeout", "30", "")))
+	assert.Error(t, ConfigSet(configFlags("work", "timeout", "abc", "")))
+	assert.Error(t, ConfigSet(configFlags("work", "skip_verify", "maybe", "")))
+	assert.Error(t, ConfigSet(configFlags("wrok", "timeout", "30", "")))
+
+	require.Nil(t, ConfigCopy(configFlags("work", "", "", "personal")))
+	assert.Error(t, ConfigCopy(configFlags("work", "", "", "personal")))
+	require.Nil(t, ConfigRename(configFlags("personal", "", "", "home")))
+	require.Nil(t, ConfigDelete(configFlags("work", "", "", "")))
+	assert.Error(t, ConfigDelete(configFlags("work", "", "", "")))
+
+	cfgm, err := cfg.NewConfigManager(configFile)
+	require.Nil(t, err)
+
+	names, err := cfgm.IDPAccountNames()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"home"}, names)
+
+	values, err := cfgm.IDPAccountSection("home")
+	require.Nil(t, err)
+
+	buf := new(bytes.Buffer)
+	require.Nil(t, writeSection(buf, "home", values))
+	assert.Equal(t, `[home]
+url = https://id.example.com
+provider = Okta
+mfa = Auto
+timeout = 30
+`, buf.String())
+}
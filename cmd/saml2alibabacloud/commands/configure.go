@@ -33,6 +33,24 @@ func Configure(configFlags *flags.CommonFlags) error {
 		return errors.Wrap(err, "failed to load idp account")
 	}
 
+	exists, err := cfgm.IDPAccountExists(idpAccountName)
+	if err != nil {
+		return errors.Wrap(err, "failed to load idp account")
+	}
+	if exists {
+		log.Printf("IDP account %s already exists, saving will overwrite its settings", idpAccountName)
+		if !configFlags.SkipPrompt {
+			answer, err := prompter.ChooseWithDefault("Overwrite the existing IDP account?", "No", []string{"Yes", "No"})
+			if err != nil {
+				return errors.Wrap(err, "failed to input configuration")
+			}
+			if answer != "Yes" {
+				log.Printf("IDP account %s left unchanged", idpAccountName)
+				return nil
+			}
+		}
+	}
+
 	// update username and hostname if supplied
 	flags.ApplyFlagOverrides(configFlags, account)
 
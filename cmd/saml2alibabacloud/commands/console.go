@@ -53,7 +53,7 @@ func Console(consoleFlags *flags.ConsoleFlags) error {
 
 	if consoleFlags.LoginExecFlags.ExecProfile != "" {
 		// Assume the desired role before generating env vars
-		alibabacloudCreds, err = assumeRoleWithProfile(alibabacloudCreds, consoleFlags.LoginExecFlags.ExecProfile, consoleFlags.LoginExecFlags.CommonFlags.SessionDuration)
+		alibabacloudCreds, err = assumeRoleWithProfile(account, alibabacloudCreds, consoleFlags.LoginExecFlags.ExecProfile, consoleFlags.LoginExecFlags.CommonFlags.SessionDuration)
 		if err != nil {
 			return errors.Wrap(err,
 				fmt.Sprintf("error acquiring credentials for profile: %s", consoleFlags.LoginExecFlags.ExecProfile))
@@ -66,7 +66,10 @@ func Console(consoleFlags *flags.ConsoleFlags) error {
 
 func loadOrLogin(account *cfg.IDPAccount, sharedCreds *alibabacloudconfig.CredentialsProvider, execFlags *flags.ConsoleFlags) (*alibabacloudconfig.AliCloudCredentials, error) {
 
-	var err error
+	role, err := profileRole(account)
+	if err != nil {
+		return nil, err
+	}
 
 	if execFlags.LoginExecFlags.Force {
 		log.Println("force login requested")
@@ -82,7 +85,12 @@ func loadOrLogin(account *cfg.IDPAccount, sharedCreds *alibabacloudconfig.Creden
 		return loginRefreshCredentials(sharedCreds, execFlags.LoginExecFlags)
 	}
 
-	ok, err := checkToken(alibabacloudCreds)
+	if !cachedCredentialsUsable(account, sharedCreds, role) {
+		log.Println("credentials expired or for another role triggering login")
+		return loginRefreshCredentials(sharedCreds, execFlags.LoginExecFlags)
+	}
+
+	ok, err := checkToken(account, alibabacloudCreds)
 	if err != nil {
 		return nil, errors.Wrap(err, "error validating token")
 	}
@@ -96,6 +104,9 @@ func loadOrLogin(account *cfg.IDPAccount, sharedCreds *alibabacloudconfig.Creden
 }
 
 func loginRefreshCredentials(sharedCreds *alibabacloudconfig.CredentialsProvider, execFlags *flags.LoginExecFlags) (*alibabacloudconfig.AliCloudCredentials, error) {
+	// the cached credentials can't be used so login must not skip over them
+	execFlags.Force = true
+
 	err := Login(execFlags)
 	if err != nil {
 		return nil, errors.Wrap(err, "error logging in")
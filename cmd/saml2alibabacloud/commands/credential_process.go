@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// CredentialProcess print the credentials of the profile in the format the AlibabaCloud CLI expects from
+// the process_command of an External mode profile, logging in first when they are expired or for another role
+func CredentialProcess(loginFlags *flags.LoginExecFlags) error {
+	// the AlibabaCloud CLI reads the credentials from stdout so anything the login prints goes to stderr,
+	// the log output is bound to stdout on windows
+	log.SetOutput(os.Stderr)
+	logrus.SetOutput(os.Stderr)
+
+	account, err := buildIdpAccount(loginFlags)
+	if err != nil {
+		return errors.Wrap(err, "error building login details")
+	}
+
+	role, err := profileRole(account)
+	if err != nil {
+		return err
+	}
+
+	sharedCreds := alibabacloudconfig.NewSharedCredentials(account.Profile)
+
+	// several external profiles for different roles may share the profile of the account
+	if !cachedCredentialsUsable(account, sharedCreds, role) {
+		loginFlags.Force = true
+
+		stdout := os.Stdout
+		os.Stdout = os.Stderr
+		err = Login(loginFlags)
+		os.Stdout = stdout
+		if err != nil {
+			return errors.Wrap(err, "error logging in")
+		}
+	}
+
+	alibabacloudCreds, err := sharedCreds.Load()
+	if err != nil {
+		return errors.Wrap(err, "error loading credentials")
+	}
+
+	return writeProcessCredentials(os.Stdout, alibabacloudCreds)
+}
+
+func writeProcessCredentials(w io.Writer, alibabacloudCreds *alibabacloudconfig.AliCloudCredentials) error {
+	return json.NewEncoder(w).Encode(alibabacloudconfig.NewProcessCredentials(alibabacloudCreds))
+}
+
+// writeExternalProfile save an External mode profile running credential-process for the account and role,
+// the credentials themselves stay cached in the profile of the account
+func writeExternalProfile(account *cfg.IDPAccount, roleARN string, loginFlags *flags.LoginExecFlags) error {
+	externalProfile := loginFlags.ExternalProfile
+	if externalProfile == account.Profile {
+		return errors.Errorf("external profile %s can't be the profile the credentials are saved to", externalProfile)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "error locating saml2alibabacloud")
+	}
+
+	err = alibabacloudconfig.NewSharedCredentials(externalProfile).SaveExternal(
+		credentialProcessCommand(executable, account, roleARN, loginFlags.CommonFlags.ConfigFile),
+		&alibabacloudconfig.AliCloudCredentials{
+			Region:       account.Region,
+			OutputFormat: account.OutputFormat,
+			Language:     account.Language,
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "error saving external profile")
+	}
+
+	log.Printf("External profile %s refreshes the credentials on demand (e.g. aliyun --profile %s sts GetCallerIdentity).", externalProfile, externalProfile)
+
+	return nil
+}
+
+// cachedRoleARN the ARN of the role the cached credentials were issued for, the external profile needs the exact
+// role as credential-process can't prompt for one
+func cachedRoleARN(account *cfg.IDPAccount, alibabacloudCreds *alibabacloudconfig.AliCloudCredentials) (string, error) {
+	if len(account.RoleChain) > 0 {
+		return "", errors.New("the SAML role of chained credentials isn't cached, login with --force to write the external profile")
+	}
+
+	roleARN, ok := assumedRoleARN(alibabacloudCreds.PrincipalARN)
+	if !ok {
+		return "", errors.Errorf("unable to tell the role of the cached credentials %s, login with --force to write the external profile", alibabacloudCreds.PrincipalARN)
+	}
+
+	return roleARN, nil
+}
+
+// credentialProcessCommand build the process_command of an External mode profile, the paths are quoted
+// when they contain spaces, e.g. C:\Program Files\saml2alibabacloud
+func credentialProcessCommand(executable string, account *cfg.IDPAccount, roleARN string, configFile string) string {
+	args := []string{quoteArg(executable)}
+	if configFile != "" {
+		args = append(args, quoteArg(fmt.Sprintf("--config=%s", configFile)))
+	}
+	args = append(args,
+		fmt.Sprintf("--idp-account=%s", account.Name),
+		"credential-process",
+		fmt.Sprintf("--profile=%s", account.Profile),
+	)
+	if roleARN != "" {
+		args = append(args, fmt.Sprintf("--role=%s", roleARN))
+	}
+	args = append(args, "--skip-prompt")
+
+	return strings.Join(args, " ")
+}
+
+// quoteArg double quote an argument of the process_command holding spaces or quotes
+func quoteArg(arg string) string {
+	if !strings.ContainsAny(arg, " \t\"") {
+		return arg
+	}
+	return `"` + strings.Replace(arg, `"`, `\"`, -1) + `"`
+}
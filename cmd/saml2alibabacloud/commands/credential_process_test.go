@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialProcessCommand(t *testing.T) {
+	account := &cfg.IDPAccount{Name: "work", Profile: "saml"}
+
+	command := credentialProcessCommand("/usr/local/bin/saml2alibabacloud", account, "acs:ram::000000000001:role/admin", "")
+	assert.Equal(t, "/usr/local/bin/saml2alibabacloud --idp-account=work credential-process --profile=saml --role=acs:ram::000000000001:role/admin --skip-prompt", command)
+
+	command = credentialProcessCommand("saml2alibabacloud", account, "", "/etc/saml2alibabacloud.cfg")
+	assert.Equal(t, "saml2alibabacloud --config=/etc/saml2alibabacloud.cfg --idp-account=work credential-process --profile=saml --skip-prompt", command)
+
+	command = credentialProcessCommand(`C:\Program Files\saml2alibabacloud\saml2alibabacloud.exe`, account, "", `C:\Users\Jane Doe\.saml2alibabacloud`)
+	assert.Equal(t, `"C:\Program Files\saml2alibabacloud\saml2alibabacloud.exe" "--config=C:\Users\Jane Doe\.saml2alibabacloud" --idp-account=work credential-process --profile=saml --skip-prompt`, command)
+}
+
+func TestCachedRoleARN(t *testing.T) {
+	account := &cfg.IDPAccount{RoleARN: "acs:ram::*:role/admin*"}
+
+	roleARN, err := cachedRoleARN(account, &alibabacloudconfig.AliCloudCredentials{PrincipalARN: "acs:ram::000000000001:assumed-role/admin/alice"})
+	require.Nil(t, err)
+	assert.Equal(t, "acs:ram::000000000001:role/admin", roleARN)
+
+	_, err = cachedRoleARN(account, &alibabacloudconfig.AliCloudCredentials{})
+	assert.Error(t, err)
+
+	account.RoleChain = []string{"acs:ram::000000000002:role/target"}
+	_, err = cachedRoleARN(account, &alibabacloudconfig.AliCloudCredentials{PrincipalARN: "acs:ram::000000000002:assumed-role/target/alice"})
+	assert.Error(t, err)
+}
+
+func TestWriteProcessCredentials(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	err := writeProcessCredentials(buf, &alibabacloudconfig.AliCloudCredentials{
+		AliCloudAccessKey:     "STS.key",
+		AliCloudSecretKey:     "secret",
+		AliCloudSecurityToken: "token",
+	})
+	require.Nil(t, err)
+	assert.Equal(t, `{"mode":"StsToken","access_key_id":"STS.key","access_key_secret":"secret","sts_token":"token"}`+"\n", buf.String())
+}
@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aliyun/saml2alibabacloud/helper/credentials"
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/pkg/errors"
+)
+
+// doctorTimeout bounds every network check so a hanging IdP shows up as a failure instead of a hang
+const doctorTimeout = 10 * time.Second
+
+// checkResult the outcome of a single doctor check, the hint tells the user what to do about a failure
+type checkResult struct {
+	Name   string
+	Passed bool
+	Detail string
+	Hint   string
+}
+
+func pass(name string, format string, args ...interface{}) *checkResult {
+	return &checkResult{Name: name, Passed: true, Detail: fmt.Sprintf(format, args...)}
+}
+
+func fail(name string, err error, hint string) *checkResult {
+	return &checkResult{Name: name, Detail: err.Error(), Hint: hint}
+}
+
+// Doctor check the idp account, the connectivity to the IdP and STS, the keychain and the AlibabaCloud CLI
+// configuration, printing a report with the result of every check
+func Doctor(loginFlags *flags.LoginExecFlags) error {
+	results := []*checkResult{}
+
+	cfgm, err := cfg.NewConfigManager(loginFlags.CommonFlags.ConfigFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load configuration")
+	}
+
+	account, err := loadIDPAccount(cfgm, loginFlags.CommonFlags.IdpAccount)
+	if notFound, ok := err.(*cfg.AccountNotFoundError); ok {
+		// carry on with the flags alone so the connectivity can still be checked
+		results = append(results, fail("IDP account", notFound, "select a configured account with --idp-account"))
+		account, err = cfg.NewIDPAccount(), nil
+	}
+	if err != nil {
+		return err
+	}
+	account.Name = loginFlags.CommonFlags.IdpAccount
+	flags.ApplyFlagOverrides(loginFlags.CommonFlags, account)
+
+	if len(results) == 0 {
+		results = append(results, checkIDPAccount(account))
+	}
+
+	// the same transport settings as a login, including skip_verify from the idp account
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if account.SkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	client := &http.Client{Transport: transport, Timeout: doctorTimeout}
+
+	if idpURL, err := url.Parse(account.URL); err == nil && idpURL.Host != "" {
+		results = append(results,
+			checkDNS("IdP DNS", idpURL.Hostname()),
+			checkProxy(idpURL),
+			checkTLS("IdP TLS", client, idpURL.String(), "check the firewall and proxy settings, use --skip-verify only if the IdP uses a private certificate authority"),
+		)
+	}
+
+	stsURL, err := stsURL(account)
+	if err != nil {
+		results = append(results, fail("STS endpoint", err, "fix sts_endpoint in the idp account or --sts-endpoint"))
+	} else {
+		results = append(results,
+			checkDNS("STS DNS", stsURL.Hostname()),
+			checkTLS("STS TLS", client, stsURL.String(), "check the firewall and proxy settings, or point --sts-endpoint at an endpoint you can reach"),
+		)
+	}
+
+	results = append(results, checkKeychain(account, loginFlags.CommonFlags.DisableKeychain), checkAlibabaCloudConfig())
+
+	failed := writeDoctorReport(os.Stdout, results)
+	if failed > 0 {
+		return errors.Errorf("%d of %d checks failed", failed, len(results))
+	}
+
+	return nil
+}
+
+func checkIDPAccount(account *cfg.IDPAccount) *checkResult {
+	if err := account.Validate(); err != nil {
+		return fail("IDP account", err, fmt.Sprintf("run saml2alibabacloud configure -a %s to set up the idp account", account.Name))
+	}
+	return pass("IDP account", "%s using %s at %s", account.Name, account.Provider, account.URL)
+}
+
+func checkDNS(name string, host string) *checkResult {
+	addrs, err := net.LookupHost(host)
+	if err != nil {
+		return fail(name, err, "check the host name and your DNS settings, a proxy may be required to reach it")
+	}
+	return pass(name, "%s resolves to %s", host, strings.Join(addrs, ", "))
+}
+
+// checkProxy report the proxy the environment configures for the url, not using one is a pass as well
+func checkProxy(u *url.URL) *checkResult {
+	proxyURL, err := http.ProxyFromEnvironment(&http.Request{URL: u})
+	if err != nil {
+		return fail("Proxy", err, "fix the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables")
+	}
+
+	settings := []string{}
+	for _, key := range []string{"HTTPS_PROXY", "HTTP_PROXY", "NO_PROXY"} {
+		value := os.Getenv(key)
+		if value == "" {
+			value = os.Getenv(strings.ToLower(key))
+		}
+		if value != "" {
+			settings = append(settings, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+
+	if proxyURL == nil {
+		if len(settings) == 0 {
+			return pass("Proxy", "no proxy configured, %s is reached directly", u.Host)
+		}
+		return pass("Proxy", "%s is reached directly (%s)", u.Host, strings.Join(settings, " "))
+	}
+	return pass("Proxy", "%s is reached through %s (%s)", u.Host, proxyURL.Host, strings.Join(settings, " "))
+}
+
+// checkTLS make a request to the url, any HTTP response means the connection and TLS handshake succeeded
+func checkTLS(name string, client *http.Client, target string, hint string) *checkResult {
+	resp, err := client.Head(target)
+	if err != nil {
+		return fail(name, err, hint)
+	}
+	defer resp.Body.Close()
+
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return pass(name, "%s answered %s without TLS", target, resp.Status)
+	}
+
+	cert := resp.TLS.PeerCertificates[0]
+	return pass(name, "%s answered %s, certificate for %s valid until %s", target, resp.Status, cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02"))
+}
+
+// stsURL the STS endpoint the idp account logs in to
+func stsURL(account *cfg.IDPAccount) (*url.URL, error) {
+	scheme, domain, err := parseSTSEndpoint(account.STSEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if domain == "" {
+		domain = "sts.aliyuncs.com"
+		if region := stsRegion(account); region != defaultSTSRegion {
+			domain = fmt.Sprintf("sts.%s.aliyuncs.com", region)
+		}
+	}
+
+	return &url.URL{Scheme: strings.ToLower(scheme), Host: domain, Path: "/"}, nil
+}
+
+// checkKeychain check the keychain backend, only listing the stored passwords so the secret itself isn't read
+func checkKeychain(account *cfg.IDPAccount, disabled bool) *checkResult {
+	if disabled {
+		return pass("Keychain", "not used, disabled with --disable-keychain")
+	}
+
+	if !credentials.SupportsStorage() {
+		return fail("Keychain", errors.New("no keychain backend available"), "the password has to be entered on every login, on linux install a secret service such as gnome-keyring or kwallet")
+	}
+
+	stored, err := credentials.HasCredentials(account.URL)
+	if err == credentials.ErrListNotSupported {
+		return pass("Keychain", "available")
+	}
+	if err != nil {
+		return fail("Keychain", err, "unlock the keychain or run with --disable-keychain")
+	}
+	if !stored {
+		return pass("Keychain", "available, no password stored for %s yet", account.URL)
+	}
+	return pass("Keychain", "available, a password is stored for %s", account.URL)
+}
+
+// checkAlibabaCloudConfig check the AlibabaCloud CLI configuration can be read and written without modifying it
+func checkAlibabaCloudConfig() *checkResult {
+	const name = "AlibabaCloud CLI config"
+	const hint = "fix the ownership and permissions of the file and its directory"
+
+	filename, err := alibabacloudconfig.ConfigFile()
+	if err != nil {
+		return fail(name, err, "set HOME to your home directory")
+	}
+
+	f, err := os.OpenFile(filename, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		// login creates the file, so the directory must be writable
+		dir := filepath.Dir(filename)
+		for {
+			if _, statErr := os.Stat(dir); !os.IsNotExist(statErr) || dir == filepath.Dir(dir) {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+		tmp, err := ioutil.TempFile(dir, ".saml2alibabacloud-doctor")
+		if err != nil {
+			return fail(name, err, hint)
+		}
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return pass(name, "%s doesn't exist yet, login will create it", filename)
+	}
+	if err != nil {
+		return fail(name, err, hint)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(ioutil.Discard, f); err != nil {
+		return fail(name, err, hint)
+	}
+
+	return pass(name, "%s is readable and writable", filename)
+}
+
+// writeDoctorReport print a line per check followed by the hint of a failed one, the number of failed checks is returned
+func writeDoctorReport(w io.Writer, results []*checkResult) int {
+	failed := 0
+
+	for _, result := range results {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+			failed++
+		}
+
+		fmt.Fprintf(w, "[%s] %s: %s\n", status, result.Name, result.Detail)
+		if !result.Passed && result.Hint != "" {
+			fmt.Fprintf(w, "       %s\n", result.Hint)
+		}
+	}
+
+	return failed
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/aliyun/saml2alibabacloud/helper/credentials"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDoctorReport(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	failed := writeDoctorReport(buf, []*checkResult{
+		pass("IdP DNS", "id.example.com resolves to %s", "10.0.0.1"),
+		fail("IdP TLS", errors.New("connection refused"), "check the firewall"),
+	})
+
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, `[PASS] IdP DNS: id.example.com resolves to 10.0.0.1
+[FAIL] IdP TLS: connection refused
+       check the firewall
+`, buf.String())
+}
+
+func TestSTSURL(t *testing.T) {
+	u, err := stsURL(&cfg.IDPAccount{})
+	require.Nil(t, err)
+	assert.Equal(t, "https://sts.aliyuncs.com/", u.String())
+
+	u, err = stsURL(&cfg.IDPAccount{Region: "ap-southeast-1"})
+	require.Nil(t, err)
+	assert.Equal(t, "https://sts.ap-southeast-1.aliyuncs.com/", u.String())
+
+	u, err = stsURL(&cfg.IDPAccount{STSEndpoint: "http://localhost:8080"})
+	require.Nil(t, err)
+	assert.Equal(t, "http://localhost:8080/", u.String())
+}
+
+func TestCheckTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	result := checkTLS("IdP TLS", ts.Client(), ts.URL, "check the firewall")
+	assert.True(t, result.Passed, result.Detail)
+
+	// the test server certificate isn't trusted by a default client
+	result = checkTLS("IdP TLS", &http.Client{}, ts.URL, "check the firewall")
+	assert.False(t, result.Passed)
+	assert.Equal(t, "check the firewall", result.Hint)
+}
+
+func TestCheckAlibabaCloudConfig(t *testing.T) {
+	home, cleanup := tempHome(t)
+	defer cleanup()
+
+	result := checkAlibabaCloudConfig()
+	assert.True(t, result.Passed, result.Detail)
+	assert.Contains(t, result.Detail, "doesn't exist yet")
+
+	require.Nil(t, os.MkdirAll(home+"/.aliyun", 0700))
+	require.Nil(t, ioutil.WriteFile(home+"/.aliyun/config.json", []byte("{}"), 0600))
+
+	result = checkAlibabaCloudConfig()
+	assert.True(t, result.Passed, result.Detail)
+	assert.Contains(t, result.Detail, "readable and writable")
+}
+
+// listingHelper a keychain which fails the test when a secret is read
+type listingHelper struct {
+	t      *testing.T
+	stored map[string]string
+}
+
+func (h *listingHelper) Add(*credentials.Credentials) error { return nil }
+
+func (h *listingHelper) Delete(string) error { return nil }
+
+func (h *listingHelper) Get(serverURL string) (string, string, error) {
+	h.t.Errorf("the secret of %s was read", serverURL)
+	return "", "", credentials.ErrCredentialsNotFound
+}
+
+func (h *listingHelper) List() (map[string]string, error) { return h.stored, nil }
+
+func (h *listingHelper) SupportsCredentialStorage() bool { return true }
+
+func TestCheckKeychain(t *testing.T) {
+	defer func(helper credentials.Helper) { credentials.CurrentHelper = helper }(credentials.CurrentHelper)
+	credentials.CurrentHelper = &listingHelper{t: t, stored: map[string]string{"https://id.example.com": "alice"}}
+
+	result := checkKeychain(&cfg.IDPAccount{URL: "https://id.example.com"}, false)
+	assert.True(t, result.Passed)
+	assert.Equal(t, "available, a password is stored for https://id.example.com", result.Detail)
+
+	result = checkKeychain(&cfg.IDPAccount{URL: "https://other.example.com"}, false)
+	assert.True(t, result.Passed)
+	assert.Equal(t, "available, no password stored for https://other.example.com yet", result.Detail)
+
+	result = checkKeychain(&cfg.IDPAccount{URL: "https://id.example.com"}, true)
+	assert.True(t, result.Passed)
+	assert.Equal(t, "not used, disabled with --disable-keychain", result.Detail)
+}
@@ -5,9 +5,9 @@ import (
 	"log"
 
 	sdkError "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
-	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/sts"
 	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
 	"github.com/aliyun/saml2alibabacloud/pkg/flags"
 	"github.com/aliyun/saml2alibabacloud/pkg/shell"
 	"github.com/pkg/errors"
@@ -39,39 +39,77 @@ func Exec(execFlags *flags.LoginExecFlags, cmdline []string) error {
 		return nil
 	}
 
-	alibabacloudCreds, err := sharedCreds.Load()
+	role, err := profileRole(account)
 	if err != nil {
-		return errors.Wrap(err, "error loading credentials")
+		return err
 	}
 
-	ok, err := checkToken(alibabacloudCreds)
-	if err != nil {
-		return errors.Wrap(err, "error validating token")
+	if !cachedCredentialsUsable(account, sharedCreds, role) {
+		log.Println("credentials expired or for another role triggering login")
+		err = Login(execFlags)
+		if err != nil {
+			return errors.Wrap(err, "error logging in")
+		}
 	}
 
-	if !ok {
-		err = Login(execFlags)
+	if execFlags.URI || execFlags.Refresh {
+		if execFlags.ExecProfile != "" {
+			return errors.New("--uri and --refresh can't be combined with --exec-profile")
+		}
+
+		refresher, err := newCredentialsRefresher(account, execFlags)
+		if err != nil {
+			return err
+		}
+
+		uri, stop, err := startCredentialsURI(refresher)
+		if err != nil {
+			return err
+		}
+		defer stop()
+
+		if !execFlags.Refresh {
+			return execCmd(execFlags, cmdline, shell.BuildCredentialsURIEnvVars(uri), shell.StaticCredentialsEnvVars...)
+		}
+
+		// refresh ahead of the expiry so the command never waits on a login to read its credentials
+		if _, err := refresher.Get(); err != nil {
+			return err
+		}
+		go refresher.keepFresh()
+
+		return execCmd(execFlags, cmdline, shell.BuildCredentialsURIEnvVars(uri), shell.StaticCredentialsEnvVars...)
 	}
+
+	alibabacloudCreds, err := sharedCreds.Load()
 	if err != nil {
-		return errors.Wrap(err, "error logging in")
+		return errors.Wrap(err, "error loading credentials")
 	}
 
 	if execFlags.ExecProfile != "" {
 		// Assume the desired role before generating env vars
-		alibabacloudCreds, err = assumeRoleWithProfile(alibabacloudCreds, execFlags.ExecProfile, execFlags.CommonFlags.SessionDuration)
+		alibabacloudCreds, err = assumeRoleWithProfile(account, alibabacloudCreds, execFlags.ExecProfile, execFlags.CommonFlags.SessionDuration)
 		if err != nil {
 			return errors.Wrap(err,
 				fmt.Sprintf("error acquiring credentials for profile: %s", execFlags.ExecProfile))
 		}
 	}
 
-	return shell.ExecShellCmd(cmdline, shell.BuildEnvVars(alibabacloudCreds, account, execFlags))
+	return execCmd(execFlags, cmdline, shell.BuildEnvVars(alibabacloudCreds, account, execFlags))
+}
+
+// execCmd run the command directly or, with --shell, through the default shell
+func execCmd(execFlags *flags.LoginExecFlags, cmdline []string, envVars []string, unset ...string) error {
+	if execFlags.Shell {
+		return shell.ExecShellCmd(cmdline, envVars, unset...)
+	}
+	return shell.ExecCmd(cmdline, envVars, unset...)
 }
 
-// assumeRoleWithProfile uses an AlibabaCloud CLI profile (via ~/.aliyun/config.json) and performs (multiple levels of) role assumption
-// This is extremely useful in the case of a central "authentication account" which then requires secondary, and
-// often tertiary, role assumptions to acquire credentials for the target role.
-func assumeRoleWithProfile(alibabacloudCreds *alibabacloudconfig.AliCloudCredentials, targetProfile string, sessionDuration int) (*alibabacloudconfig.AliCloudCredentials, error) {
+// assumeRoleWithProfile uses an AlibabaCloud CLI profile (via ~/.aliyun/config.json) and assumes the role it names.
+// This is useful in the case of a central "authentication account" which then requires a secondary role
+// assumption to acquire credentials for the target role, longer chains are configured with role_chain.
+func assumeRoleWithProfile(account *cfg.IDPAccount, alibabacloudCreds *alibabacloudconfig.AliCloudCredentials, targetProfile string, sessionDuration int) (*alibabacloudconfig.AliCloudCredentials, error) {
 
 	// get target profile
 	sharedCreds := alibabacloudconfig.NewSharedCredentials(targetProfile)
@@ -93,41 +131,15 @@ func assumeRoleWithProfile(alibabacloudCreds *alibabacloudconfig.AliCloudCredent
 		return nil, errors.Wrap(err, "error loading target credentials")
 	}
 
-	// AlibabaCloud session config with verbose errors on chained credential errors
-	client, err := sts.NewClientWithStsToken("cn-hangzhou", alibabacloudCreds.AliCloudAccessKey, alibabacloudCreds.AliCloudSecretKey, alibabacloudCreds.AliCloudSecurityToken)
-	if err != nil {
-		return nil, err
-	}
-	client.AppendUserAgent("saml2alibabacloud", "0.0.5")
-	request := sts.CreateAssumeRoleRequest()
-	request.RoleSessionName = targetCreds.AliCloudSessionToken
-	request.RoleArn = targetCreds.PrincipalARN
-	request.DurationSeconds = requests.NewInteger(sessionDuration)
-
-	// use an STS client to perform the multiple role assumptions
-	response, err := client.AssumeRole(request)
-	if err != nil {
-		return nil, err
-	}
-
-	return &alibabacloudconfig.AliCloudCredentials{
-		AliCloudAccessKey:     response.Credentials.AccessKeyId,
-		AliCloudSecretKey:     response.Credentials.AccessKeySecret,
-		AliCloudSessionToken:  targetCreds.AliCloudSessionToken,
-		AliCloudSecurityToken: response.Credentials.SecurityToken,
-		PrincipalARN:          response.AssumedRoleUser.Arn,
-	}, nil
+	return assumeRole(account, alibabacloudCreds, targetCreds.PrincipalARN, targetCreds.AliCloudSessionToken, sessionDuration, account.SessionPolicy)
 }
 
-func checkToken(alibabacloudCreds *alibabacloudconfig.AliCloudCredentials) (bool, error) {
-	client, err := sts.NewClientWithStsToken("cn-hangzhou", alibabacloudCreds.AliCloudAccessKey, alibabacloudCreds.AliCloudSecretKey, alibabacloudCreds.AliCloudSecurityToken)
-
+func checkToken(account *cfg.IDPAccount, alibabacloudCreds *alibabacloudconfig.AliCloudCredentials) (bool, error) {
+	client, err := newSTSClient(account, alibabacloudCreds)
 	if err != nil {
 		return false, err
 	}
 
-	client.AppendUserAgent("saml2alibabacloud", "0.0.5")
-
 	request := sts.CreateGetCallerIdentityRequest()
 
 	_, err = client.GetCallerIdentity(request)
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tempHome point HOME at a new temporary directory, the returned func restores HOME and removes the directory
+func tempHome(t *testing.T) (string, func()) {
+	home, err := ioutil.TempDir("", "saml2alibabacloud")
+	require.Nil(t, err)
+
+	previous := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+
+	return home, func() {
+		os.Setenv("HOME", previous)
+		os.RemoveAll(home)
+	}
+}
@@ -1,20 +1,33 @@
 package commands
 
 import (
-	b64 "encoding/base64"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
+	"text/tabwriter"
 
 	saml2alibabacloud "github.com/aliyun/saml2alibabacloud"
 	"github.com/aliyun/saml2alibabacloud/helper/credentials"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
 	"github.com/aliyun/saml2alibabacloud/pkg/flags"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
 // ListRoles will list available role ARNs
-func ListRoles(loginFlags *flags.LoginExecFlags) error {
+func ListRoles(listRolesFlags *flags.ListRolesFlags) error {
+
+	loginFlags := listRolesFlags.LoginExecFlags
+
+	// the roles are parsed from stdout so the log output, bound to stdout on windows, goes to stderr
+	if listRolesFlags.Output != "" {
+		log.SetOutput(os.Stderr)
+		logrus.SetOutput(os.Stderr)
+	}
 
 	logger := logrus.WithField("command", "list")
 
@@ -53,6 +66,11 @@ func ListRoles(loginFlags *flags.LoginExecFlags) error {
 		os.Exit(1)
 	}
 
+	data, err := verifySignature(samlAssertion, account)
+	if err != nil {
+		return errors.Wrap(err, "error verifying SAML response signature")
+	}
+
 	if !loginFlags.CommonFlags.DisableKeychain {
 		err = credentials.SaveCredentials(loginDetails.URL, loginDetails.Username, loginDetails.Password)
 		if err != nil {
@@ -60,11 +78,6 @@ func ListRoles(loginFlags *flags.LoginExecFlags) error {
 		}
 	}
 
-	data, err := b64.StdEncoding.DecodeString(samlAssertion)
-	if err != nil {
-		return errors.Wrap(err, "error decoding saml assertion")
-	}
-
 	roles, err := saml2alibabacloud.ExtractRamRoles(data)
 	if err != nil {
 		return errors.Wrap(err, "error parsing AlibabaCloud roles")
@@ -80,40 +93,42 @@ func ListRoles(loginFlags *flags.LoginExecFlags) error {
 		return errors.Wrap(err, "error parsing AlibabaCloud roles")
 	}
 
-	if err := listRoles(alibabacloudRoles, samlAssertion, loginFlags); err != nil {
+	if err := listRoles(alibabacloudRoles, samlAssertion, account, listRolesFlags); err != nil {
 		return errors.Wrap(err, "Failed to list roles")
 	}
 
 	return nil
 }
 
-func listRoles(alibabacloudRoles []*saml2alibabacloud.RamRole, samlAssertion string, loginFlags *flags.LoginExecFlags) error {
-	if len(alibabacloudRoles) == 1 {
+func listRoles(alibabacloudRoles []*saml2alibabacloud.RamRole, samlAssertion string, account *cfg.IDPAccount, listRolesFlags *flags.ListRolesFlags) error {
+	if len(alibabacloudRoles) == 0 {
+		return errors.New("no roles available")
+	}
+
+	if len(alibabacloudRoles) == 1 && !account.OfflineRoles {
+		if listRolesFlags.Output != "" {
+			// the role selection page is skipped for a single role so the account comes from the role ARN
+			alibabacloudAccounts := saml2alibabacloud.BuildAlibabaCloudAccounts(alibabacloudRoles, account.AccountAliases)
+			return writeRoles(os.Stdout, listRolesFlags.Output, alibabacloudAccounts)
+		}
+
 		log.Println("")
 		log.Println("Only one role to assume. Will be automatically assumed on login")
 		log.Println(alibabacloudRoles[0].RoleARN)
 		return nil
-	} else if len(alibabacloudRoles) == 0 {
-		return errors.New("no roles available")
 	}
 
-	samlAssertionData, err := b64.StdEncoding.DecodeString(samlAssertion)
+	alibabacloudAccounts, err := resolveAccounts(alibabacloudRoles, samlAssertion, account)
 	if err != nil {
-		return errors.Wrap(err, "error decoding saml assertion")
+		return err
 	}
 
-	aud, err := saml2alibabacloud.ExtractDestinationURL(samlAssertionData)
-	if err != nil {
-		return errors.Wrap(err, "error parsing destination url")
-	}
+	saml2alibabacloud.AssignPrincipals(alibabacloudRoles, alibabacloudAccounts)
 
-	alibabacloudAccounts, err := saml2alibabacloud.ParseAlibabaCloudAccounts(aud, samlAssertion)
-	if err != nil {
-		return errors.Wrap(err, "error parsing AlibabaCloud role accounts")
+	if listRolesFlags.Output != "" {
+		return writeRoles(os.Stdout, listRolesFlags.Output, alibabacloudAccounts)
 	}
 
-	saml2alibabacloud.AssignPrincipals(alibabacloudRoles, alibabacloudAccounts)
-
 	log.Println("")
 	for _, account := range alibabacloudAccounts {
 		fmt.Println(account.Name)
@@ -125,3 +140,66 @@ func listRoles(alibabacloudRoles []*saml2alibabacloud.RamRole, samlAssertion str
 
 	return nil
 }
+
+// roleEntry a single row of the list-roles output
+type roleEntry struct {
+	AccountID    string `json:"account_id"`
+	AccountAlias string `json:"account_alias"`
+	RoleName     string `json:"role_name"`
+	RoleARN      string `json:"role_arn"`
+	ProviderARN  string `json:"provider_arn"`
+}
+
+var roleEntryHeader = []string{"ACCOUNT_ID", "ACCOUNT_ALIAS", "ROLE_NAME", "ROLE_ARN", "PROVIDER_ARN"}
+
+func (e roleEntry) fields() []string {
+	return []string{e.AccountID, e.AccountAlias, e.RoleName, e.RoleARN, e.ProviderARN}
+}
+
+// writeRoles write the roles of the accounts to w in the json, csv or table format
+func writeRoles(w io.Writer, format string, alibabacloudAccounts []*saml2alibabacloud.AlibabaCloudAccount) error {
+	entries := []roleEntry{}
+	for _, account := range alibabacloudAccounts {
+		for _, role := range account.Roles {
+			accountID := account.ID
+			if accountID == "" {
+				accountID = role.AccountID()
+			}
+			entries = append(entries, roleEntry{
+				AccountID:    accountID,
+				AccountAlias: account.Alias,
+				RoleName:     role.RoleName(),
+				RoleARN:      role.RoleARN,
+				ProviderARN:  role.PrincipalARN,
+			})
+		}
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(roleEntryHeader); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := cw.Write(entry.fields()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(roleEntryHeader, "\t"))
+		for _, entry := range entries {
+			fmt.Fprintln(tw, strings.Join(entry.fields(), "\t"))
+		}
+		return tw.Flush()
+	default:
+		return errors.Errorf("unsupported output format: %s", format)
+	}
+}
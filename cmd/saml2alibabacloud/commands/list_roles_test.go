@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	saml2alibabacloud "github.com/aliyun/saml2alibabacloud"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAccounts() []*saml2alibabacloud.AlibabaCloudAccount {
+	return []*saml2alibabacloud.AlibabaCloudAccount{
+		{
+			Name:  "sharedservices(000000000002)",
+			ID:    "000000000002",
+			Alias: "sharedservices",
+			Roles: []*saml2alibabacloud.RamRole{
+				{
+					Name:         "Development",
+					RoleARN:      "acs:ram::000000000002:role/Development",
+					PrincipalARN: "acs:ram::000000000002:saml-provider/example-idp",
+				},
+			},
+		},
+	}
+}
+
+func TestWriteRolesJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeRoles(&buf, "json", testAccounts())
+	require.Nil(t, err)
+
+	var entries []map[string]string
+	err = json.Unmarshal(buf.Bytes(), &entries)
+	require.Nil(t, err)
+	assert.Equal(t, []map[string]string{
+		{
+			"account_id":    "000000000002",
+			"account_alias": "sharedservices",
+			"role_name":     "Development",
+			"role_arn":      "acs:ram::000000000002:role/Development",
+			"provider_arn":  "acs:ram::000000000002:saml-provider/example-idp",
+		},
+	}, entries)
+}
+
+func TestWriteRolesCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeRoles(&buf, "csv", testAccounts())
+	require.Nil(t, err)
+	assert.Equal(t, "ACCOUNT_ID,ACCOUNT_ALIAS,ROLE_NAME,ROLE_ARN,PROVIDER_ARN\n"+
+		"000000000002,sharedservices,Development,acs:ram::000000000002:role/Development,acs:ram::000000000002:saml-provider/example-idp\n", buf.String())
+}
+
+func TestWriteRolesTable(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeRoles(&buf, "table", testAccounts())
+	require.Nil(t, err)
+	assert.Contains(t, buf.String(), "ACCOUNT_ID    ACCOUNT_ALIAS")
+	assert.Contains(t, buf.String(), "000000000002  sharedservices")
+}
+
+func TestWriteRolesUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeRoles(&buf, "yaml", testAccounts())
+	assert.Error(t, err)
+}
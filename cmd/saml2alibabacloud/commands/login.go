@@ -1,10 +1,13 @@
 package commands
 
 import (
+	"crypto/rsa"
 	b64 "encoding/base64"
 	"log"
 	"os"
+	"time"
 
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/sts"
 	saml2alibabacloud "github.com/aliyun/saml2alibabacloud"
 	"github.com/aliyun/saml2alibabacloud/helper/credentials"
@@ -12,6 +15,7 @@ import (
 	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
 	"github.com/aliyun/saml2alibabacloud/pkg/creds"
 	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/aliyun/saml2alibabacloud/pkg/rolehistory"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -26,8 +30,85 @@ func Login(loginFlags *flags.LoginExecFlags) error {
 		return errors.Wrap(err, "error building login details")
 	}
 
+	if len(account.RoleProfiles) > 0 {
+		if loginFlags.ExternalProfile != "" {
+			return errors.New("--write-external-profile can't be combined with ROLE=PROFILE roles")
+		}
+		return loginRoles(account, loginFlags)
+	}
+
 	sharedCreds := alibabacloudconfig.NewSharedCredentials(account.Profile)
 
+	if !loginFlags.Force && cachedCredentialsUsable(account, sharedCreds, account.RoleARN) {
+		log.Println("credentials are not expired skipping")
+		previousCreds, err := sharedCreds.Load()
+		if err != nil {
+			log.Println("Unable to load cached credentials")
+		} else {
+			logger.Debug("Credentials expire at ", previousCreds.Expires)
+		}
+		if loginFlags.ExternalProfile != "" {
+			if err != nil {
+				return errors.Wrap(err, "error loading cached credentials")
+			}
+			roleARN, err := cachedRoleARN(account, previousCreds)
+			if err != nil {
+				return err
+			}
+			return writeExternalProfile(account, roleARN, loginFlags)
+		}
+		return nil
+	}
+
+	samlAssertion, data, err := authenticate(account, loginFlags)
+	if err != nil {
+		return err
+	}
+
+	role, err := selectRamRole(samlAssertion, data, account)
+	if err != nil {
+		return errors.Wrap(err, "Failed to assume role, please check whether you are permitted to assume the given role for the AlibabaCloud STS service")
+	}
+
+	log.Println("Selected role:", role.RoleARN)
+
+	sessionDuration, err := resolveSessionDuration(account, data)
+	if err != nil {
+		return err
+	}
+
+	alibabacloudCreds, err := loginToStsUsingRole(account, role, samlAssertion, sessionDuration)
+	if err != nil {
+		return errors.Wrap(err, "error logging into AlibabaCloud role using saml assertion")
+	}
+
+	if len(account.RoleChain) > 0 {
+		alibabacloudCreds, err = assumeRoleChain(account, alibabacloudCreds)
+		if err != nil {
+			return err
+		}
+	}
+
+	alibabacloudCreds.SessionPolicyHash = sessionPolicyHash(account.SessionPolicy)
+
+	err = saveCredentials(alibabacloudCreds, sharedCreds)
+	if err != nil {
+		return err
+	}
+
+	if loginFlags.ExternalProfile != "" {
+		return writeExternalProfile(account, role.RoleARN, loginFlags)
+	}
+
+	return nil
+}
+
+// authenticate to the IdP and return the SAML assertion along with the decoded response the roles and
+// attributes are read from, only its signed element when the signature is verified
+func authenticate(account *cfg.IDPAccount, loginFlags *flags.LoginExecFlags) (string, []byte, error) {
+
+	logger := logrus.WithField("command", "login")
+
 	loginDetails, err := resolveLoginDetails(account, loginFlags)
 	if err != nil {
 		log.Printf("%+v", err)
@@ -36,21 +117,21 @@ func Login(loginFlags *flags.LoginExecFlags) error {
 
 	err = loginDetails.Validate()
 	if err != nil {
-		return errors.Wrap(err, "error validating login details")
+		return "", nil, errors.Wrap(err, "error validating login details")
 	}
 
 	logger.WithField("idpAccount", account).Debug("building provider")
 
 	provider, err := saml2alibabacloud.NewSAMLClient(account)
 	if err != nil {
-		return errors.Wrap(err, "error building IdP client")
+		return "", nil, errors.Wrap(err, "error building IdP client")
 	}
 
 	log.Printf("Authenticating as %s ...", loginDetails.Username)
 
 	samlAssertion, err := provider.Authenticate(loginDetails)
 	if err != nil {
-		return errors.Wrap(err, "error authenticating to IdP")
+		return "", nil, errors.Wrap(err, "error authenticating to IdP")
 
 	}
 
@@ -61,39 +142,53 @@ func Login(loginFlags *flags.LoginExecFlags) error {
 		os.Exit(1)
 	}
 
+	data, err := verifySignature(samlAssertion, account)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "error verifying SAML response signature")
+	}
+
 	if !loginFlags.CommonFlags.DisableKeychain {
 		err = credentials.SaveCredentials(loginDetails.URL, loginDetails.Username, loginDetails.Password)
 		if err != nil {
-			return errors.Wrap(err, "error storing password in keychain")
+			return "", nil, errors.Wrap(err, "error storing password in keychain")
 		}
 	}
 
-	role, err := selectRamRole(samlAssertion, account)
-	if err != nil {
-		return errors.Wrap(err, "Failed to assume role, please check whether you are permitted to assume the given role for the AlibabaCloud STS service")
-	}
-
-	log.Println("Selected role:", role.RoleARN)
+	return samlAssertion, data, nil
+}
 
-	alibabacloudCreds, err := loginToStsUsingRole(account, role, samlAssertion)
-	if err != nil {
-		return errors.Wrap(err, "error logging into AlibabaCloud role using saml assertion")
+// loadIDPAccount load the selected idp account, a missing one is an error unless it is the default account
+// which can be supplied entirely with flags
+func loadIDPAccount(cfgm *cfg.ConfigManager, idpAccountName string) (*cfg.IDPAccount, error) {
+	if idpAccountName == cfg.DefaultIDPAccount {
+		account, err := cfgm.LoadIDPAccount(idpAccountName)
+		return account, errors.Wrap(err, "failed to load idp account")
 	}
 
-	return saveCredentials(alibabacloudCreds, sharedCreds)
+	account, err := cfgm.LookupIDPAccount(idpAccountName)
+	if _, ok := err.(*cfg.AccountNotFoundError); ok {
+		return nil, err
+	}
+	return account, errors.Wrap(err, "failed to load idp account")
 }
 
 func buildIdpAccount(loginFlags *flags.LoginExecFlags) (*cfg.IDPAccount, error) {
+	if loginFlags.CommonFlags.Policy != "" && loginFlags.CommonFlags.PolicyFile != "" {
+		return nil, errors.New("--policy can't be combined with --policy-file")
+	}
+
 	cfgm, err := cfg.NewConfigManager(loginFlags.CommonFlags.ConfigFile)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to load configuration")
 	}
 
-	account, err := cfgm.LoadIDPAccount(loginFlags.CommonFlags.IdpAccount)
+	account, err := loadIDPAccount(cfgm, loginFlags.CommonFlags.IdpAccount)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to load idp account")
+		return nil, err
 	}
 
+	account.Name = loginFlags.CommonFlags.IdpAccount
+
 	// update username and hostname if supplied
 	flags.ApplyFlagOverrides(loginFlags.CommonFlags, account)
 
@@ -102,6 +197,14 @@ func buildIdpAccount(loginFlags *flags.LoginExecFlags) (*cfg.IDPAccount, error)
 		return nil, errors.Wrap(err, "failed to validate account")
 	}
 
+	// validate the session policy before authenticating, later it is only attached to the STS requests
+	if account.SessionPolicy != "" {
+		account.SessionPolicy, err = loadSessionPolicy(account.SessionPolicy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return account, nil
 }
 
@@ -160,12 +263,81 @@ func resolveLoginDetails(account *cfg.IDPAccount, loginFlags *flags.LoginExecFla
 	return loginDetails, nil
 }
 
-func selectRamRole(samlAssertion string, account *cfg.IDPAccount) (*saml2alibabacloud.RamRole, error) {
+// verifySignature checks the SAML response signature when the idp account is configured with IdP certificates,
+// the decoded response is returned or, when the signature is checked, only the element it covers
+func verifySignature(samlAssertion string, account *cfg.IDPAccount) ([]byte, error) {
+	if !saml2alibabacloud.SignatureVerificationEnabled(account) {
+		return decodeSAMLResponse(samlAssertion, account)
+	}
+
+	data, err := b64.StdEncoding.DecodeString(samlAssertion)
+	if err != nil {
+		return nil, errors.Wrap(err, "error decoding saml assertion")
+	}
+
+	certs, err := saml2alibabacloud.LoadIDPCertificates(account)
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading IdP certificates")
+	}
+
+	verified, err := saml2alibabacloud.VerifySignature(data, certs)
+	if err == nil {
+		// a signed response may still carry an encrypted assertion
+		return decryptSAMLResponse(verified, account)
+	}
+	if err != saml2alibabacloud.ErrEncryptedAssertion {
+		return nil, err
+	}
+
+	// the assertion carries the signature so check it once decrypted
+	data, err = decodeSAMLResponse(samlAssertion, account)
+	if err != nil {
+		return nil, err
+	}
+
+	return saml2alibabacloud.VerifySignature(data, certs)
+}
+
+// decodeSAMLResponse decodes the SAML response and decrypts the assertion if it is encrypted, the
+// original response is left untouched as that is what is sent to STS
+func decodeSAMLResponse(samlAssertion string, account *cfg.IDPAccount) ([]byte, error) {
 	data, err := b64.StdEncoding.DecodeString(samlAssertion)
 	if err != nil {
 		return nil, errors.Wrap(err, "error decoding saml assertion")
 	}
 
+	return decryptSAMLResponse(data, account)
+}
+
+func decryptSAMLResponse(data []byte, account *cfg.IDPAccount) ([]byte, error) {
+	var key *rsa.PrivateKey
+	var err error
+	if account.SPPrivateKey != "" {
+		key, err = saml2alibabacloud.LoadSPPrivateKey(account.SPPrivateKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "error loading SP private key")
+		}
+	}
+
+	data, err = saml2alibabacloud.DecryptAssertion(data, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "error decrypting saml assertion")
+	}
+
+	return data, nil
+}
+
+func selectRamRole(samlAssertion string, data []byte, account *cfg.IDPAccount) (*saml2alibabacloud.RamRole, error) {
+	alibabacloudRoles, err := extractRamRoles(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolveRole(alibabacloudRoles, samlAssertion, account)
+}
+
+// extractRamRoles parse the roles the decoded SAML response permits
+func extractRamRoles(data []byte) ([]*saml2alibabacloud.RamRole, error) {
 	roles, err := saml2alibabacloud.ExtractRamRoles(data)
 	if err != nil {
 		return nil, errors.Wrap(err, "error parsing alicloud roles")
@@ -182,21 +354,34 @@ func selectRamRole(samlAssertion string, account *cfg.IDPAccount) (*saml2alibaba
 		return nil, errors.Wrap(err, "error parsing AlibabaCloud roles")
 	}
 
-	return resolveRole(alibabacloudRoles, samlAssertion, account)
+	return alibabacloudRoles, nil
 }
 
-func resolveRole(alibabacloudRoles []*saml2alibabacloud.RamRole, samlAssertion string, account *cfg.IDPAccount) (*saml2alibabacloud.RamRole, error) {
-	var role = new(saml2alibabacloud.RamRole)
+// resolveAccounts group the roles by account, either from the AlibabaCloud role selection page or, in offline
+// mode, from the role ARNs using the aliases in the configuration file
+func resolveAccounts(alibabacloudRoles []*saml2alibabacloud.RamRole, samlAssertion string, account *cfg.IDPAccount) ([]*saml2alibabacloud.AlibabaCloudAccount, error) {
+	if account.OfflineRoles {
+		alibabacloudAccounts := saml2alibabacloud.BuildAlibabaCloudAccounts(alibabacloudRoles, account.AccountAliases)
+		if !account.EnrichAliases {
+			return alibabacloudAccounts, nil
+		}
 
-	if len(alibabacloudRoles) == 1 {
-		if account.RoleARN != "" {
-			return saml2alibabacloud.LocateRole(alibabacloudRoles, account.RoleARN)
+		// the role selection page only adds the aliases so failing to read it isn't fatal
+		pageAccounts, err := fetchAccounts(samlAssertion)
+		if err != nil {
+			log.Printf("unable to read the account aliases from the role selection page: %v", err)
+			return alibabacloudAccounts, nil
 		}
-		return alibabacloudRoles[0], nil
-	} else if len(alibabacloudRoles) == 0 {
-		return nil, errors.New("no roles available")
+		saml2alibabacloud.EnrichAlibabaCloudAccounts(alibabacloudAccounts, pageAccounts)
+
+		return alibabacloudAccounts, nil
 	}
 
+	return fetchAccounts(samlAssertion)
+}
+
+// fetchAccounts read the accounts from the AlibabaCloud role selection page
+func fetchAccounts(samlAssertion string) ([]*saml2alibabacloud.AlibabaCloudAccount, error) {
 	samlAssertionData, err := b64.StdEncoding.DecodeString(samlAssertion)
 	if err != nil {
 		return nil, errors.Wrap(err, "error decoding saml assertion")
@@ -211,40 +396,106 @@ func resolveRole(alibabacloudRoles []*saml2alibabacloud.RamRole, samlAssertion s
 	if err != nil {
 		return nil, errors.Wrap(err, "error parsing AlibabaCloud role accounts")
 	}
+
+	return alibabacloudAccounts, nil
+}
+
+func resolveRole(alibabacloudRoles []*saml2alibabacloud.RamRole, samlAssertion string, account *cfg.IDPAccount) (*saml2alibabacloud.RamRole, error) {
+	var role = new(saml2alibabacloud.RamRole)
+
+	if len(alibabacloudRoles) == 0 {
+		return nil, errors.New("no roles available")
+	}
+
+	// the role can be an alias, glob or regular expression, only the matching roles are offered
+	if account.RoleARN != "" {
+		matched, err := saml2alibabacloud.MatchRoles(alibabacloudRoles, account.RoleARN, account.RoleAliases)
+		if err != nil {
+			return nil, err
+		}
+		alibabacloudRoles = matched
+	}
+
+	if len(alibabacloudRoles) == 1 {
+		return alibabacloudRoles[0], nil
+	}
+
+	alibabacloudAccounts, err := resolveAccounts(alibabacloudRoles, samlAssertion, account)
+	if err != nil {
+		return nil, err
+	}
+
+	alibabacloudAccounts = saml2alibabacloud.FilterAlibabaCloudAccounts(alibabacloudAccounts, alibabacloudRoles)
 	if len(alibabacloudAccounts) == 0 {
 		return nil, errors.New("no accounts available")
 	}
 
 	// saml2alibabacloud.AssignPrincipals(alibabacloudRoles, alibabacloudAccounts)
 
-	if account.RoleARN != "" {
-		return saml2alibabacloud.LocateRole(alibabacloudRoles, account.RoleARN)
+	history := loadRoleHistory(account)
+
+	var recent []string
+	if history != nil {
+		recent = history.Recent(account.Name)
 	}
 
 	for {
-		role, err = saml2alibabacloud.PromptForRamRoleSelection(alibabacloudAccounts)
+		role, err = saml2alibabacloud.PromptForRamRoleSelection(alibabacloudAccounts, recent)
 		if err == nil {
 			break
 		}
 		log.Println("error selecting role, try again")
 	}
 
+	if history != nil {
+		history.Add(account.Name, role.RoleARN, roleHistorySize(account))
+		if err := history.Save(); err != nil {
+			log.Printf("unable to save the recently chosen roles: %v", err)
+		}
+	}
+
 	return role, nil
 }
 
-func loginToStsUsingRole(account *cfg.IDPAccount, role *saml2alibabacloud.RamRole, samlAssertion string) (*alibabacloudconfig.AliCloudCredentials, error) {
+// loadRoleHistory load the roles recently chosen, nil is returned when the history is disabled or can't be read
+func loadRoleHistory(account *cfg.IDPAccount) *rolehistory.History {
+	if roleHistorySize(account) <= 0 {
+		return nil
+	}
 
-	client, err := sts.NewClientWithAccessKey("cn-hangzhou", "saml2alibabacloud", "0.0.5")
+	history, err := rolehistory.Load(rolehistory.DefaultPath)
+	if err != nil {
+		log.Printf("unable to load the recently chosen roles: %v", err)
+		return nil
+	}
+
+	return history
+}
+
+func roleHistorySize(account *cfg.IDPAccount) int {
+	if account.RecentRoles == 0 {
+		return rolehistory.DefaultSize
+	}
+	return account.RecentRoles
+}
+
+func loginToStsUsingRole(account *cfg.IDPAccount, role *saml2alibabacloud.RamRole, samlAssertion string, sessionDuration int) (*alibabacloudconfig.AliCloudCredentials, error) {
+
+	client, err := newSTSClient(account, nil)
 	if err != nil {
 		return nil, err
 	}
-	client.AppendUserAgent("saml2alibabacloud", "0.0.5")
 
 	request := sts.CreateAssumeRoleWithSAMLRequest()
-	request.Scheme = "https"
 	request.RoleArn = role.RoleARN
 	request.SAMLAssertion = samlAssertion
 	request.SAMLProviderArn = role.PrincipalARN
+	request.DurationSeconds = requests.NewInteger(sessionDuration)
+
+	// with a role chain the policy scopes the last hop, scoping the SAML session would prevent the hops
+	if len(account.RoleChain) == 0 {
+		request.Policy = account.SessionPolicy
+	}
 
 	log.Println("Requesting AlibabaCloud credentials using SAML assertion")
 
@@ -253,15 +504,44 @@ func loginToStsUsingRole(account *cfg.IDPAccount, role *saml2alibabacloud.RamRol
 		return nil, errors.Wrap(err, "error retrieving STS credentials using SAML")
 	}
 
+	expires, err := time.Parse(time.RFC3339, response.Credentials.Expiration)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing STS credentials expiration")
+	}
+
 	return &alibabacloudconfig.AliCloudCredentials{
 		AliCloudAccessKey:     response.Credentials.AccessKeyId,
 		AliCloudSecretKey:     response.Credentials.AccessKeySecret,
 		AliCloudSecurityToken: response.Credentials.SecurityToken,
 		PrincipalARN:          response.AssumedRoleUser.Arn,
 		Region:                account.Region,
+		OutputFormat:          account.OutputFormat,
+		Language:              account.Language,
+		Expires:               expires.Local(),
 	}, nil
 }
 
+// resolveSessionDuration works out the session duration to request from STS, the duration from the
+// config file or flag is capped at the SessionDuration the IdP permits in the decoded SAML response
+func resolveSessionDuration(account *cfg.IDPAccount, data []byte) (int, error) {
+	assertionDuration, err := saml2alibabacloud.ExtractSessionDuration(data)
+	if err != nil {
+		return 0, errors.Wrap(err, "error parsing session duration from saml assertion")
+	}
+
+	sessionDuration := account.SessionDuration
+	if sessionDuration == 0 {
+		sessionDuration = cfg.DefaultSessionDuration
+	}
+
+	if assertionDuration > 0 && int64(sessionDuration) > assertionDuration {
+		log.Printf("Requested session duration of %ds exceeds the %ds permitted by the IdP, using %ds", sessionDuration, assertionDuration, assertionDuration)
+		sessionDuration = int(assertionDuration)
+	}
+
+	return sessionDuration, nil
+}
+
 func saveCredentials(alibabacloudCreds *alibabacloudconfig.AliCloudCredentials, sharedCreds *alibabacloudconfig.CredentialsProvider) error {
 	err := sharedCreds.Save(alibabacloudCreds)
 	if err != nil {
@@ -271,7 +551,7 @@ func saveCredentials(alibabacloudCreds *alibabacloudconfig.AliCloudCredentials,
 	log.Println("Logged in as:", alibabacloudCreds.PrincipalARN)
 	log.Println("")
 	log.Println("Your new access key pair has been stored in the AlibabaCloud CLI configuration")
-	// log.Printf("Note that it will expire at %v", alibabacloudCreds.Expires)
+	log.Printf("Note that it will expire at %v", alibabacloudCreds.Expires)
 	log.Println("To use this credential, call the AlibabaCloud CLI with the --profile option (e.g. aliyun --profile", sharedCreds.Profile, "sts GetCallerIdentity --region=cn-hangzhou).")
 
 	return nil
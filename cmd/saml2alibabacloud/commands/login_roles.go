@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	saml2alibabacloud "github.com/aliyun/saml2alibabacloud"
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/pkg/errors"
+)
+
+// roleProfile a role to log in to and the AlibabaCloud CLI profile its credentials are saved to
+type roleProfile struct {
+	Selector string
+	Profile  string
+	Role     *saml2alibabacloud.RamRole
+}
+
+// parseRoleProfiles parse the ROLE=PROFILE pairs supplied with --role or role_profiles
+func parseRoleProfiles(values []string) ([]*roleProfile, error) {
+	roleProfiles := []*roleProfile{}
+	profiles := make(map[string]bool)
+
+	for _, value := range values {
+		selector, profile, ok := cfg.SplitRoleProfile(value)
+		if !ok || selector == "" || profile == "" {
+			return nil, errors.Errorf("invalid role %s, expected ROLE=PROFILE", value)
+		}
+
+		if profiles[profile] {
+			return nil, errors.Errorf("profile %s is used by more than one role", profile)
+		}
+		profiles[profile] = true
+
+		roleProfiles = append(roleProfiles, &roleProfile{Selector: selector, Profile: profile})
+	}
+
+	return roleProfiles, nil
+}
+
+// loginRoles authenticate to the IdP once and log in to every role using the same SAML assertion
+func loginRoles(account *cfg.IDPAccount, loginFlags *flags.LoginExecFlags) error {
+	roleProfiles, err := parseRoleProfiles(account.RoleProfiles)
+	if err != nil {
+		return err
+	}
+
+	if !loginFlags.Force && allCachedCredentialsUsable(account, roleProfiles) {
+		log.Println("credentials are not expired skipping")
+		return nil
+	}
+
+	samlAssertion, data, err := authenticate(account, loginFlags)
+	if err != nil {
+		return err
+	}
+
+	alibabacloudRoles, err := extractRamRoles(data)
+	if err != nil {
+		return err
+	}
+
+	sessionDuration, err := resolveSessionDuration(account, data)
+	if err != nil {
+		return err
+	}
+
+	for _, rp := range roleProfiles {
+		matched, err := saml2alibabacloud.MatchRoles(alibabacloudRoles, rp.Selector, account.RoleAliases)
+		if err != nil {
+			return err
+		}
+		if len(matched) != 1 {
+			return errors.Errorf("role %s for profile %s matches %d roles, it must match exactly one", rp.Selector, rp.Profile, len(matched))
+		}
+		rp.Role = matched[0]
+	}
+
+	results := loginToStsUsingRoles(account, roleProfiles, samlAssertion, sessionDuration)
+
+	// the AlibabaCloud CLI configuration is a single file so the credentials are saved one at a time
+	failed := []string{}
+	for i, rp := range roleProfiles {
+		if results[i].err != nil {
+			log.Printf("error logging into %s for profile %s: %v", rp.Role.RoleARN, rp.Profile, results[i].err)
+			failed = append(failed, rp.Profile)
+			continue
+		}
+
+		results[i].creds.SessionPolicyHash = sessionPolicyHash(account.SessionPolicy)
+		if err := saveCredentials(results[i].creds, alibabacloudconfig.NewSharedCredentials(rp.Profile)); err != nil {
+			log.Printf("error saving credentials for profile %s: %v", rp.Profile, err)
+			failed = append(failed, rp.Profile)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to log in to the profiles: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+type loginResult struct {
+	creds *alibabacloudconfig.AliCloudCredentials
+	err   error
+}
+
+// loginToStsUsingRoles call AssumeRoleWithSAML for all the roles concurrently, the results are in the same order as the roles
+func loginToStsUsingRoles(account *cfg.IDPAccount, roleProfiles []*roleProfile, samlAssertion string, sessionDuration int) []loginResult {
+	results := make([]loginResult, len(roleProfiles))
+
+	var wg sync.WaitGroup
+	for i, rp := range roleProfiles {
+		wg.Add(1)
+		go func(i int, role *saml2alibabacloud.RamRole) {
+			defer wg.Done()
+			results[i].creds, results[i].err = loginToStsUsingRole(account, role, samlAssertion, sessionDuration)
+		}(i, rp.Role)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// profileRole the role requested for the profile the command reads the credentials from, with role_profiles the
+// login only saves to those profiles so it must be one of them
+func profileRole(account *cfg.IDPAccount) (string, error) {
+	if len(account.RoleProfiles) == 0 {
+		return account.RoleARN, nil
+	}
+
+	roleProfiles, err := parseRoleProfiles(account.RoleProfiles)
+	if err != nil {
+		return "", err
+	}
+
+	profiles := []string{}
+	for _, rp := range roleProfiles {
+		if rp.Profile == account.Profile {
+			return rp.Selector, nil
+		}
+		profiles = append(profiles, rp.Profile)
+	}
+
+	return "", errors.Errorf("profile %s isn't one of the ROLE=PROFILE roles, select one of %s with -p", account.Profile, strings.Join(profiles, ", "))
+}
+
+func allCachedCredentialsUsable(account *cfg.IDPAccount, roleProfiles []*roleProfile) bool {
+	for _, rp := range roleProfiles {
+		if !cachedCredentialsUsable(account, alibabacloudconfig.NewSharedCredentials(rp.Profile), rp.Selector) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	b64 "encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	saml2alibabacloud "github.com/aliyun/saml2alibabacloud"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRoleProfiles(t *testing.T) {
+	roleProfiles, err := parseRoleProfiles([]string{"acs:ram::000000000001:role/admin=prod", " dev-admin = dev "})
+	require.Nil(t, err)
+	require.Len(t, roleProfiles, 2)
+	assert.Equal(t, "acs:ram::000000000001:role/admin", roleProfiles[0].Selector)
+	assert.Equal(t, "prod", roleProfiles[0].Profile)
+	assert.Equal(t, "dev-admin", roleProfiles[1].Selector)
+	assert.Equal(t, "dev", roleProfiles[1].Profile)
+
+	roleProfiles, err = parseRoleProfiles([]string{"/env=prod/=prod"})
+	require.Nil(t, err)
+	require.Len(t, roleProfiles, 1)
+	assert.Equal(t, "/env=prod/", roleProfiles[0].Selector)
+	assert.Equal(t, "prod", roleProfiles[0].Profile)
+
+	for _, values := range [][]string{
+		{"acs:ram::000000000001:role/admin"},
+		{"/env=prod/"},
+		{"=prod"},
+		{"admin="},
+		{"admin=prod", "readonly=prod"},
+	} {
+		_, err := parseRoleProfiles(values)
+		assert.Error(t, err, values)
+	}
+}
+
+func TestLoginToStsUsingRoles(t *testing.T) {
+	var calls int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		roleArn := r.URL.Query().Get("RoleArn")
+		if strings.HasSuffix(roleArn, "NonProd") {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"RequestId": "6894B13B", "Code": "NoPermission", "Message": "denied"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{
+			"RequestId": "6894B13B-6D71-4EF5-88FA-F32781734A7F",
+			"Credentials": {
+				"AccessKeyId": "STS.L4aBSCSJVMuKg5U1vFDw",
+				"AccessKeySecret": "wyLTSmsyPGP1ohvvw8xYgB29dlGI8KMiH2pKCNZ9",
+				"SecurityToken": "CAESrAIIARKAAShQquMnLIlbvEcIxO6wCoqJufs8sWwie",
+				"Expiration": "2015-04-09T11:52:19Z"
+			},
+			"AssumedRoleUser": {
+				"AssumedRoleId": "344584339364951186:alice",
+				"Arn": "%s/alice"
+			}
+		}`, strings.Replace(roleArn, ":role/", ":assumed-role/", 1))
+	}))
+	defer ts.Close()
+
+	data, err := ioutil.ReadFile("../../../testdata/assertion.xml")
+	require.Nil(t, err)
+	samlAssertion := b64.StdEncoding.EncodeToString(data)
+
+	idpa := cfg.NewIDPAccount()
+	idpa.STSEndpoint = ts.URL
+
+	roleProfiles := []*roleProfile{
+		{Profile: "build", Role: &saml2alibabacloud.RamRole{
+			RoleARN:      "acs:ram::123123123123:role/Ali-CloudAdminOps-Build",
+			PrincipalARN: "acs:ram::123123123123:saml-provider/ExampleADFS",
+		}},
+		{Profile: "nonprod", Role: &saml2alibabacloud.RamRole{
+			RoleARN:      "acs:ram::123123123123:role/Ali-CloudAdminOps-NonProd",
+			PrincipalARN: "acs:ram::123123123123:saml-provider/ExampleADFS",
+		}},
+	}
+
+	results := loginToStsUsingRoles(idpa, roleProfiles, samlAssertion, cfg.DefaultSessionDuration)
+	require.Len(t, results, 2)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	require.Nil(t, results[0].err)
+	assert.Equal(t, "acs:ram::123123123123:assumed-role/Ali-CloudAdminOps-Build/alice", results[0].creds.PrincipalARN)
+
+	assert.Error(t, results[1].err)
+	assert.Nil(t, results[1].creds)
+}
+
+func TestProfileRole(t *testing.T) {
+	account := &cfg.IDPAccount{Profile: "saml", RoleARN: "acs:ram::000000000001:role/admin"}
+
+	role, err := profileRole(account)
+	require.Nil(t, err)
+	assert.Equal(t, "acs:ram::000000000001:role/admin", role)
+
+	account.RoleProfiles = []string{"acs:ram::000000000001:role/admin=prod", "/role/read=only$/=readonly"}
+	_, err = profileRole(account)
+	assert.EqualError(t, err, "profile saml isn't one of the ROLE=PROFILE roles, select one of prod, readonly with -p")
+
+	account.Profile = "readonly"
+	role, err = profileRole(account)
+	require.Nil(t, err)
+	assert.Equal(t, "/role/read=only$/", role)
+}
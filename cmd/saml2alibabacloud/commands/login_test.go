@@ -1,6 +1,8 @@
 package commands
 
 import (
+	b64 "encoding/base64"
+	"io/ioutil"
 	"testing"
 
 	saml2alibabacloud "github.com/aliyun/saml2alibabacloud"
@@ -8,6 +10,7 @@ import (
 	"github.com/aliyun/saml2alibabacloud/pkg/creds"
 	"github.com/aliyun/saml2alibabacloud/pkg/flags"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestResolveLoginDetailsWithFlags(t *testing.T) {
@@ -43,3 +46,136 @@ func TestResolveRoleSingleEntry(t *testing.T) {
 	assert.Empty(t, err)
 	assert.Equal(t, got, adminRole)
 }
+
+func TestResolveRoleSelector(t *testing.T) {
+	alibabacloudRoles, err := saml2alibabacloud.ParseRamRoles([]string{
+		"acs:ram::000000000001:role/admin,acs:ram::000000000001:saml-provider/example-idp",
+		"acs:ram::000000000001:role/readonly,acs:ram::000000000001:saml-provider/example-idp",
+		"acs:ram::000000000002:role/admin,acs:ram::000000000002:saml-provider/example-idp",
+	})
+	require.Nil(t, err)
+
+	account := cfg.NewIDPAccount()
+	account.RoleAliases = map[string]string{"prod-admin": "acs:ram::000000000002:role/admin"}
+
+	for selector, expected := range map[string]string{
+		"prod-admin":      "acs:ram::000000000002:role/admin",
+		"*:role/readonly": "acs:ram::000000000001:role/readonly",
+		"/0002:.*admin$/": "acs:ram::000000000002:role/admin",
+	} {
+		account.RoleARN = selector
+
+		got, err := resolveRole(alibabacloudRoles, "", account)
+		assert.Nil(t, err, selector)
+		assert.Equal(t, expected, got.RoleARN, selector)
+	}
+
+	account.RoleARN = "*:role/missing"
+	_, err = resolveRole(alibabacloudRoles, "", account)
+	assert.Error(t, err)
+}
+
+func TestVerifySignature(t *testing.T) {
+
+	data, err := ioutil.ReadFile("../../../testdata/assertion_signed.xml")
+	assert.Nil(t, err)
+	samlAssertion := b64.StdEncoding.EncodeToString(data)
+
+	// verification is skipped when no IdP certificate is configured
+	decoded, err := verifySignature(samlAssertion, cfg.NewIDPAccount())
+	assert.Nil(t, err)
+	assert.Equal(t, data, decoded)
+
+	idpa := cfg.NewIDPAccount()
+	idpa.IDPCertificate = "../../../testdata/idp_cert.pem"
+
+	verified, err := verifySignature(samlAssertion, idpa)
+	assert.Nil(t, err)
+
+	roles, err := extractRamRoles(verified)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, roles)
+
+	unsigned, err := ioutil.ReadFile("../../../testdata/assertion_unsigned.xml")
+	assert.Nil(t, err)
+
+	_, err = verifySignature(b64.StdEncoding.EncodeToString(unsigned), idpa)
+	assert.Equal(t, saml2alibabacloud.ErrMissingSignature, err)
+}
+
+func TestDecodeSAMLResponseEncrypted(t *testing.T) {
+
+	data, err := ioutil.ReadFile("../../../testdata/assertion_encrypted.xml")
+	assert.Nil(t, err)
+	samlAssertion := b64.StdEncoding.EncodeToString(data)
+
+	idpa := cfg.NewIDPAccount()
+	idpa.IDPCertificate = "../../../testdata/idp_cert.pem"
+
+	_, err = decodeSAMLResponse(samlAssertion, idpa)
+	assert.Error(t, err)
+
+	idpa.SPPrivateKey = "../../../testdata/sp_key.pem"
+
+	decrypted, err := decodeSAMLResponse(samlAssertion, idpa)
+	assert.Nil(t, err)
+
+	roles, err := saml2alibabacloud.ExtractRamRoles(decrypted)
+	assert.Nil(t, err)
+	assert.Len(t, roles, 2)
+
+	verified, err := verifySignature(samlAssertion, idpa)
+	assert.Nil(t, err)
+
+	roles, err = saml2alibabacloud.ExtractRamRoles(verified)
+	assert.Nil(t, err)
+	assert.Len(t, roles, 2)
+}
+
+func TestResolveSessionDuration(t *testing.T) {
+
+	data, err := ioutil.ReadFile("../../../testdata/assertion.xml")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name            string
+		sessionDuration int
+		want            int
+	}{
+		{name: "default", sessionDuration: 0, want: cfg.DefaultSessionDuration},
+		{name: "within-assertion-limit", sessionDuration: 7200, want: 7200},
+		{name: "capped-by-assertion", sessionDuration: 43200, want: 28800},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idpa := cfg.NewIDPAccount()
+			idpa.SessionDuration = tt.sessionDuration
+
+			got, err := resolveSessionDuration(idpa, data)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolveAccountsOffline(t *testing.T) {
+	alibabacloudRoles, err := saml2alibabacloud.ParseRamRoles([]string{
+		"acs:ram::000000000001:role/Development,acs:ram::000000000001:saml-provider/example-idp",
+		"acs:ram::000000000002:role/Production,acs:ram::000000000002:saml-provider/example-idp",
+	})
+	require.Nil(t, err)
+
+	account := &cfg.IDPAccount{
+		OfflineRoles:   true,
+		EnrichAliases:  true,
+		AccountAliases: map[string]string{"000000000002": "prod"},
+	}
+
+	// the assertion can't be posted to the role selection page, the aliases from the configuration are kept
+	alibabacloudAccounts, err := resolveAccounts(alibabacloudRoles, "not-an-assertion", account)
+	require.Nil(t, err)
+	require.Len(t, alibabacloudAccounts, 2)
+	assert.Equal(t, "000000000001", alibabacloudAccounts[0].Name)
+	assert.Equal(t, "prod(000000000002)", alibabacloudAccounts[1].Name)
+	assert.Equal(t, "Production", alibabacloudAccounts[1].Roles[0].Name)
+}
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"log"
+
+	"github.com/aliyun/saml2alibabacloud/helper/credentials"
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/pkg/errors"
+)
+
+// Logout remove the profiles saved by login and optionally the password stored for the idp account,
+// the IdP cookies only live in memory for the duration of a login so there are none to wipe
+func Logout(logoutFlags *flags.LogoutFlags) error {
+	var profiles []string
+
+	if logoutFlags.All {
+		managed, err := alibabacloudconfig.ManagedProfiles()
+		if err != nil {
+			return errors.Wrap(err, "error loading saved profiles")
+		}
+		profiles = managed
+	}
+
+	if !logoutFlags.All || logoutFlags.DeletePassword {
+		account, err := loadLogoutAccount(logoutFlags.LoginExecFlags)
+		if err != nil {
+			return err
+		}
+
+		if !logoutFlags.All {
+			profiles, err = accountProfiles(account.Profile, account.RoleProfiles)
+			if err != nil {
+				return err
+			}
+		}
+
+		if logoutFlags.DeletePassword {
+			if err := credentials.DeleteCredentials(account.URL, account.Provider); err != nil {
+				return errors.Wrap(err, "error deleting stored password")
+			}
+			log.Println("Deleted the stored password for", account.URL)
+		}
+	}
+
+	for _, profile := range profiles {
+		if err := alibabacloudconfig.NewSharedCredentials(profile).Delete(); err != nil {
+			return errors.Wrapf(err, "error removing profile %s", profile)
+		}
+		log.Println("Removed profile", profile)
+	}
+
+	return nil
+}
+
+// loadLogoutAccount load the idp account without validating it or loading its session policy, neither
+// matters to logout and an unrelated configuration error mustn't keep the credentials around
+func loadLogoutAccount(loginFlags *flags.LoginExecFlags) (*cfg.IDPAccount, error) {
+	cfgm, err := cfg.NewConfigManager(loginFlags.CommonFlags.ConfigFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load configuration")
+	}
+
+	account, err := loadIDPAccount(cfgm, loginFlags.CommonFlags.IdpAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	account.Name = loginFlags.CommonFlags.IdpAccount
+	flags.ApplyFlagOverrides(loginFlags.CommonFlags, account)
+
+	return account, nil
+}
+
+// accountProfiles the profiles login saves the credentials of the idp account to
+func accountProfiles(profile string, roleProfiles []string) ([]string, error) {
+	if len(roleProfiles) == 0 {
+		return []string{profile}, nil
+	}
+
+	parsed, err := parseRoleProfiles(roleProfiles)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := []string{}
+	for _, rp := range parsed {
+		profiles = append(profiles, rp.Profile)
+	}
+	return profiles, nil
+}
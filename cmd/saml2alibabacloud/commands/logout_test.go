@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogoutAll(t *testing.T) {
+	_, cleanup := tempHome(t)
+	defer cleanup()
+
+	for _, profile := range []string{"saml", "admin"} {
+		err := alibabacloudconfig.NewSharedCredentials(profile).Save(&alibabacloudconfig.AliCloudCredentials{
+			AliCloudAccessKey: "STS.key",
+			Expires:           time.Now().Add(time.Hour),
+		})
+		require.Nil(t, err)
+	}
+
+	err := Logout(&flags.LogoutFlags{All: true, LoginExecFlags: &flags.LoginExecFlags{CommonFlags: &flags.CommonFlags{}}})
+	require.Nil(t, err)
+
+	profiles, err := alibabacloudconfig.ManagedProfiles()
+	require.Nil(t, err)
+	assert.Empty(t, profiles)
+}
+
+func TestLogoutIgnoresAccountErrors(t *testing.T) {
+	home, cleanup := tempHome(t)
+	defer cleanup()
+
+	// neither the missing url nor the missing policy file matter to logout
+	configFile := filepath.Join(home, ".saml2alibabacloud")
+	err := ioutil.WriteFile(configFile, []byte(`[work]
+alibabacloud_profile = work
+session_policy = missing-policy.json
+`), 0600)
+	require.Nil(t, err)
+
+	for _, profile := range []string{"work", "other"} {
+		err = alibabacloudconfig.NewSharedCredentials(profile).Save(&alibabacloudconfig.AliCloudCredentials{
+			AliCloudAccessKey: "STS.key",
+			Expires:           time.Now().Add(time.Hour),
+		})
+		require.Nil(t, err)
+	}
+
+	err = Logout(&flags.LogoutFlags{LoginExecFlags: &flags.LoginExecFlags{CommonFlags: &flags.CommonFlags{ConfigFile: configFile, IdpAccount: "work"}}})
+	require.Nil(t, err)
+
+	profiles, err := alibabacloudconfig.ManagedProfiles()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"other"}, profiles)
+}
+
+func TestAccountProfiles(t *testing.T) {
+	profiles, err := accountProfiles("saml", nil)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"saml"}, profiles)
+
+	profiles, err = accountProfiles("saml", []string{"admin=prod-admin", "dev*=dev"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"prod-admin", "dev"}, profiles)
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+)
+
+// sessionPolicy the subset of an RAM policy document checked before it is sent to STS
+type sessionPolicy struct {
+	Version   string            `json:"Version"`
+	Statement []json.RawMessage `json:"Statement"`
+}
+
+// loadSessionPolicy read the session policy, which is either an inline JSON document or the path
+// to a file holding one, and return it validated and compacted
+func loadSessionPolicy(value string) (string, error) {
+	data := []byte(strings.TrimSpace(value))
+
+	if !bytes.HasPrefix(data, []byte("{")) {
+		filename, err := homedir.Expand(string(data))
+		if err != nil {
+			return "", err
+		}
+
+		data, err = ioutil.ReadFile(filename)
+		if err != nil {
+			return "", errors.Wrap(err, "error reading session policy file")
+		}
+	}
+
+	if err := validateSessionPolicy(data); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return "", errors.Wrap(err, "invalid session policy")
+	}
+
+	return buf.String(), nil
+}
+
+func validateSessionPolicy(data []byte) error {
+	var policy sessionPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return errors.Wrap(err, "invalid session policy")
+	}
+
+	if policy.Version != "1" {
+		return errors.Errorf("invalid session policy, Version must be \"1\" not %q", policy.Version)
+	}
+
+	if len(policy.Statement) == 0 {
+		return errors.New("invalid session policy, at least one Statement is required")
+	}
+
+	return nil
+}
+
+// sessionPolicyHash identify the session policy in the metadata of the saved credentials, so they are only
+// reused for the same policy, no policy has an empty hash
+func sessionPolicyHash(policy string) string {
+	if policy == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(policy))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSessionPolicy = `{
+  "Version": "1",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": "oss:GetObject",
+      "Resource": "acs:oss:*:*:example-bucket/*"
+    }
+  ]
+}`
+
+func TestLoadSessionPolicy(t *testing.T) {
+	expected := `{"Version":"1","Statement":[{"Effect":"Allow","Action":"oss:GetObject","Resource":"acs:oss:*:*:example-bucket/*"}]}`
+
+	policy, err := loadSessionPolicy(testSessionPolicy)
+	require.Nil(t, err)
+	assert.Equal(t, expected, policy)
+
+	f, err := ioutil.TempFile("", "policy")
+	require.Nil(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(testSessionPolicy)
+	require.Nil(t, err)
+	f.Close()
+
+	policy, err = loadSessionPolicy(f.Name())
+	require.Nil(t, err)
+	assert.Equal(t, expected, policy)
+}
+
+func TestLoadSessionPolicyInvalid(t *testing.T) {
+	for _, value := range []string{
+		`{"Version": "1", "Statement": [`,
+		`{"Version": "1", "Statement": []}`,
+		`{"Statement": [{"Effect": "Allow", "Action": "*", "Resource": "*"}]}`,
+		`{"Version": "1", "Statement": {"Effect": "Allow"}}`,
+		"does-not-exist.json",
+	} {
+		_, err := loadSessionPolicy(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestBuildIdpAccountPolicyAndPolicyFile(t *testing.T) {
+	commonFlags := &flags.CommonFlags{Policy: `{"Version":"1","Statement":[]}`, PolicyFile: "policy.json"}
+
+	_, err := buildIdpAccount(&flags.LoginExecFlags{CommonFlags: commonFlags})
+	require.EqualError(t, err, "--policy can't be combined with --policy-file")
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"strings"
+
+	saml2alibabacloud "github.com/aliyun/saml2alibabacloud"
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+)
+
+// cachedCredentialsUsable tell whether the credentials saved to the profile can be used instead of logging in,
+// they must not be expired, must be scoped down by the same session policy and must be for the requested role,
+// which is either an ARN, an alias, a glob or a regular expression
+func cachedCredentialsUsable(account *cfg.IDPAccount, sharedCreds *alibabacloudconfig.CredentialsProvider, selector string) bool {
+	if sharedCreds.Expired() {
+		return false
+	}
+
+	alibabacloudCreds, err := sharedCreds.Load()
+	if err != nil {
+		return false
+	}
+
+	return cachedCredentialsMatch(account, alibabacloudCreds, selector)
+}
+
+// cachedCredentialsMatch check the cached credentials were issued with the session policy of the account and for
+// the requested role
+func cachedCredentialsMatch(account *cfg.IDPAccount, alibabacloudCreds *alibabacloudconfig.AliCloudCredentials, selector string) bool {
+	if alibabacloudCreds.SessionPolicyHash != sessionPolicyHash(account.SessionPolicy) {
+		return false
+	}
+
+	return cachedRoleMatches(account, alibabacloudCreds, selector)
+}
+
+// cachedRoleMatches check the role the cached credentials were issued for, with a role_chain they are
+// for the last hop of the chain
+func cachedRoleMatches(account *cfg.IDPAccount, alibabacloudCreds *alibabacloudconfig.AliCloudCredentials, selector string) bool {
+	if len(account.RoleChain) > 0 {
+		hops, err := parseRoleChain(account.RoleChain)
+		if err != nil || len(hops) == 0 {
+			return false
+		}
+		selector = hops[len(hops)-1].RoleARN
+	}
+
+	// without a role the user picks one, any of them will do
+	if selector == "" {
+		return true
+	}
+
+	roleARN, ok := assumedRoleARN(alibabacloudCreds.PrincipalARN)
+	if !ok {
+		return false
+	}
+
+	// the assumed role ARN keeps the case of the role name while the role ARN of the assertion is lower case
+	for _, candidate := range []string{roleARN, strings.ToLower(roleARN)} {
+		matched, err := saml2alibabacloud.MatchRoles([]*saml2alibabacloud.RamRole{{RoleARN: candidate}}, selector, account.RoleAliases)
+		if err == nil && len(matched) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// assumedRoleARN the ARN of the role of an assumed role ARN, acs:ram::<uid>:assumed-role/<role>/<session>
+// becomes acs:ram::<uid>:role/<role>
+func assumedRoleARN(principalARN string) (string, bool) {
+	tokens := strings.Split(principalARN, "/")
+	if len(tokens) != 3 || !strings.HasSuffix(tokens[0], ":assumed-role") {
+		return "", false
+	}
+
+	return strings.TrimSuffix(tokens[0], "assumed-role") + "role/" + tokens[1], true
+}
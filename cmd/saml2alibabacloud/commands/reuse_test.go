@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedCredentialsUsable(t *testing.T) {
+	_, cleanup := tempHome(t)
+	defer cleanup()
+
+	sharedCreds := alibabacloudconfig.NewSharedCredentials("saml")
+	account := &cfg.IDPAccount{RoleAliases: map[string]string{"admin": "acs:ram::000000000001:role/admin"}}
+
+	// nothing saved yet
+	assert.False(t, cachedCredentialsUsable(account, sharedCreds, ""))
+
+	err := sharedCreds.Save(&alibabacloudconfig.AliCloudCredentials{
+		AliCloudAccessKey: "STS.key",
+		PrincipalARN:      "acs:ram::000000000001:assumed-role/Admin/alice",
+		Expires:           time.Now().Add(time.Hour),
+	})
+	require.Nil(t, err)
+
+	assert.True(t, cachedCredentialsUsable(account, sharedCreds, ""))
+	assert.True(t, cachedCredentialsUsable(account, sharedCreds, "acs:ram::000000000001:role/admin"))
+	assert.True(t, cachedCredentialsUsable(account, sharedCreds, "admin"))
+	assert.True(t, cachedCredentialsUsable(account, sharedCreds, "acs:ram::*:role/adm*"))
+	assert.True(t, cachedCredentialsUsable(account, sharedCreds, "/role/admin$/"))
+	assert.False(t, cachedCredentialsUsable(account, sharedCreds, "acs:ram::000000000001:role/readonly"))
+	assert.False(t, cachedCredentialsUsable(account, sharedCreds, "acs:ram::000000000002:role/admin"))
+
+	// the credentials are for the last hop of the chain
+	account.RoleChain = []string{"acs:ram::000000000002:role/admin"}
+	assert.False(t, cachedCredentialsUsable(account, sharedCreds, "acs:ram::000000000001:role/admin"))
+	account.RoleChain = []string{"acs:ram::000000000001:role/admin"}
+	assert.True(t, cachedCredentialsUsable(account, sharedCreds, "acs:ram::000000000003:role/saml"))
+
+	// the cached session isn't scoped down by the policy
+	account.SessionPolicy = `{"Version":"1","Statement":[{"Effect":"Allow","Action":"oss:Get*","Resource":"*"}]}`
+	assert.False(t, cachedCredentialsUsable(account, sharedCreds, ""))
+
+	err = sharedCreds.Save(&alibabacloudconfig.AliCloudCredentials{
+		AliCloudAccessKey: "STS.key",
+		PrincipalARN:      "acs:ram::000000000001:assumed-role/Admin/alice",
+		Expires:           time.Now().Add(time.Hour),
+		SessionPolicyHash: sessionPolicyHash(account.SessionPolicy),
+	})
+	require.Nil(t, err)
+	assert.True(t, cachedCredentialsUsable(account, sharedCreds, ""))
+
+	account.SessionPolicy = `{"Version":"1","Statement":[{"Effect":"Allow","Action":"oss:*","Resource":"*"}]}`
+	assert.False(t, cachedCredentialsUsable(account, sharedCreds, ""))
+	account.SessionPolicy = ""
+
+	err = sharedCreds.Save(&alibabacloudconfig.AliCloudCredentials{
+		AliCloudAccessKey: "STS.key",
+		PrincipalARN:      "acs:ram::000000000001:assumed-role/Admin/alice",
+		Expires:           time.Now().Add(-time.Hour),
+	})
+	require.Nil(t, err)
+	assert.False(t, cachedCredentialsUsable(account, sharedCreds, ""))
+}
+
+func TestAssumedRoleARN(t *testing.T) {
+	roleARN, ok := assumedRoleARN("acs:ram::000000000001:assumed-role/admin/alice")
+	assert.True(t, ok)
+	assert.Equal(t, "acs:ram::000000000001:role/admin", roleARN)
+
+	_, ok = assumedRoleARN("acs:ram::000000000001:role/admin")
+	assert.False(t, ok)
+
+	_, ok = assumedRoleARN("")
+	assert.False(t, ok)
+}
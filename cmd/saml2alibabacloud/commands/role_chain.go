@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/sts"
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/pkg/errors"
+)
+
+// defaultRoleSessionName the session name used for a hop when neither the hop nor the assumed role provide one
+const defaultRoleSessionName = "saml2alibabacloud"
+
+// roleChainHop a role assumed with AssumeRole using the credentials of the previous hop
+type roleChainHop struct {
+	RoleARN     string
+	SessionName string
+	Duration    int
+}
+
+// parseRoleChain parse the role_chain entries, each is a role ARN optionally followed by
+// ;session=<name> and ;duration=<seconds>
+func parseRoleChain(values []string) ([]*roleChainHop, error) {
+	hops := []*roleChainHop{}
+
+	for _, value := range values {
+		tokens := strings.Split(value, ";")
+
+		hop := &roleChainHop{RoleARN: strings.TrimSpace(tokens[0])}
+		if hop.RoleARN == "" {
+			return nil, errors.Errorf("invalid role_chain entry %q, missing role ARN", value)
+		}
+
+		for _, token := range tokens[1:] {
+			kv := strings.SplitN(token, "=", 2)
+			if len(kv) != 2 {
+				return nil, errors.Errorf("invalid role_chain option %q in %s", token, hop.RoleARN)
+			}
+
+			switch key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]); key {
+			case "session":
+				hop.SessionName = val
+			case "duration":
+				duration, err := strconv.Atoi(val)
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid role_chain duration in %s", hop.RoleARN)
+				}
+				hop.Duration = duration
+			default:
+				return nil, errors.Errorf("unknown role_chain option %q in %s", key, hop.RoleARN)
+			}
+		}
+
+		hops = append(hops, hop)
+	}
+
+	return hops, nil
+}
+
+// assumeRoleChain hop through the roles in the role_chain of the idp account starting from the supplied credentials
+func assumeRoleChain(account *cfg.IDPAccount, alibabacloudCreds *alibabacloudconfig.AliCloudCredentials) (*alibabacloudconfig.AliCloudCredentials, error) {
+	hops, err := parseRoleChain(account.RoleChain)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, hop := range hops {
+		sessionName := hop.SessionName
+		if sessionName == "" {
+			sessionName = roleSessionName(alibabacloudCreds.PrincipalARN)
+		}
+
+		duration := hop.Duration
+		if duration == 0 {
+			duration = cfg.DefaultSessionDuration
+		}
+
+		log.Println("Assuming chained role:", hop.RoleARN)
+
+		policy := ""
+		if i == len(hops)-1 {
+			policy = account.SessionPolicy
+		}
+
+		alibabacloudCreds, err = assumeRole(account, alibabacloudCreds, hop.RoleARN, sessionName, duration, policy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error assuming chained role %s", hop.RoleARN)
+		}
+	}
+
+	return alibabacloudCreds, nil
+}
+
+// assumeRole call AssumeRole with the supplied credentials, the session is scoped down by the policy when one is supplied
+func assumeRole(account *cfg.IDPAccount, alibabacloudCreds *alibabacloudconfig.AliCloudCredentials, roleARN string, sessionName string, sessionDuration int, policy string) (*alibabacloudconfig.AliCloudCredentials, error) {
+	client, err := newSTSClient(account, alibabacloudCreds)
+	if err != nil {
+		return nil, err
+	}
+
+	request := sts.CreateAssumeRoleRequest()
+	request.RoleSessionName = sessionName
+	request.RoleArn = roleARN
+	if sessionDuration > 0 {
+		request.DurationSeconds = requests.NewInteger(sessionDuration)
+	}
+	request.Policy = policy
+
+	response, err := client.AssumeRole(request)
+	if err != nil {
+		return nil, err
+	}
+
+	expires, err := time.Parse(time.RFC3339, response.Credentials.Expiration)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing STS credentials expiration")
+	}
+
+	return &alibabacloudconfig.AliCloudCredentials{
+		AliCloudAccessKey:     response.Credentials.AccessKeyId,
+		AliCloudSecretKey:     response.Credentials.AccessKeySecret,
+		AliCloudSessionToken:  sessionName,
+		AliCloudSecurityToken: response.Credentials.SecurityToken,
+		PrincipalARN:          response.AssumedRoleUser.Arn,
+		Region:                account.Region,
+		OutputFormat:          account.OutputFormat,
+		Language:              account.Language,
+		Expires:               expires.Local(),
+	}, nil
+}
+
+// roleSessionName reuse the session name of an assumed role ARN, acs:ram::<uid>:assumed-role/<role>/<session>,
+// so the user stays visible in the ActionTrail of every hop
+func roleSessionName(assumedRoleARN string) string {
+	tokens := strings.Split(assumedRoleARN, "/")
+	if len(tokens) == 3 && strings.Contains(tokens[0], ":assumed-role") && tokens[2] != "" {
+		return tokens[2]
+	}
+	return defaultRoleSessionName
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRoleChain(t *testing.T) {
+	hops, err := parseRoleChain([]string{
+		"acs:ram::000000000001:role/hop",
+		" acs:ram::000000000002:role/target ; session=deploy ; duration=900",
+	})
+	require.Nil(t, err)
+	require.Len(t, hops, 2)
+	assert.Equal(t, &roleChainHop{RoleARN: "acs:ram::000000000001:role/hop"}, hops[0])
+	assert.Equal(t, &roleChainHop{RoleARN: "acs:ram::000000000002:role/target", SessionName: "deploy", Duration: 900}, hops[1])
+
+	for _, value := range []string{
+		";session=deploy",
+		"acs:ram::000000000002:role/target;session",
+		"acs:ram::000000000002:role/target;duration=soon",
+		"acs:ram::000000000002:role/target;policy=x",
+	} {
+		_, err := parseRoleChain([]string{value})
+		assert.Error(t, err, value)
+	}
+}
+
+func TestAssumeRoleChain(t *testing.T) {
+	requests := []string{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "AssumeRole", r.URL.Query().Get("Action"))
+
+		roleArn := r.URL.Query().Get("RoleArn")
+		sessionName := r.URL.Query().Get("RoleSessionName")
+		requests = append(requests, fmt.Sprintf("%s %s %s %s", roleArn, sessionName, r.URL.Query().Get("DurationSeconds"), r.URL.Query().Get("Policy")))
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{
+			"RequestId": "6894B13B-6D71-4EF5-88FA-F32781734A7F",
+			"Credentials": {
+				"AccessKeyId": "STS.%s",
+				"AccessKeySecret": "secret",
+				"SecurityToken": "token",
+				"Expiration": "2015-04-09T11:52:19Z"
+			},
+			"AssumedRoleUser": {
+				"AssumedRoleId": "344584339364951186:%s",
+				"Arn": "%s/%s"
+			}
+		}`, sessionName, sessionName, strings.Replace(roleArn, ":role/", ":assumed-role/", 1), sessionName)
+	}))
+	defer ts.Close()
+
+	idpa := cfg.NewIDPAccount()
+	idpa.STSEndpoint = ts.URL
+	idpa.RoleChain = []string{
+		"acs:ram::000000000001:role/hop",
+		"acs:ram::000000000002:role/target;session=deploy;duration=900",
+	}
+	idpa.SessionPolicy = `{"Version":"1","Statement":[]}`
+
+	alibabacloudCreds, err := assumeRoleChain(idpa, &alibabacloudconfig.AliCloudCredentials{
+		AliCloudAccessKey:     "STS.alice",
+		AliCloudSecretKey:     "secret",
+		AliCloudSecurityToken: "token",
+		PrincipalARN:          "acs:ram::000000000000:assumed-role/saml/alice",
+	})
+	require.Nil(t, err)
+
+	assert.Equal(t, []string{
+		"acs:ram::000000000001:role/hop alice 3600 ",
+		`acs:ram::000000000002:role/target deploy 900 {"Version":"1","Statement":[]}`,
+	}, requests)
+	assert.Equal(t, "STS.deploy", alibabacloudCreds.AliCloudAccessKey)
+	assert.Equal(t, "acs:ram::000000000002:assumed-role/target/deploy", alibabacloudCreds.PrincipalARN)
+	assert.Equal(t, int64(1428580339), alibabacloudCreds.Expires.Unix())
+}
+
+func TestRoleSessionName(t *testing.T) {
+	assert.Equal(t, "alice", roleSessionName("acs:ram::000000000000:assumed-role/saml/alice"))
+	assert.Equal(t, defaultRoleSessionName, roleSessionName("acs:ram::000000000000:role/saml"))
+	assert.Equal(t, defaultRoleSessionName, roleSessionName(""))
+}
@@ -1,51 +1,136 @@
 package commands
 
 import (
+	"io"
 	"log"
 	"os"
+	"strings"
 	"text/template"
+	"time"
 
 	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
 	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/aliyun/saml2alibabacloud/pkg/shell"
 	"github.com/pkg/errors"
 )
 
-const bashTmpl = `export ALIBABA_CLOUD_ACCESS_KEY_ID="{{ .AliCloudAccessKey }}"
-export ALIBABA_CLOUD_ACCESS_KEY_SECRET="{{ .AliCloudSecretKey }}"
-export ALIBABA_CLOUD_SESSION_TOKEN="{{ .AliCloudSessionToken }}"
-export ALIBABA_CLOUD_SECURITY_TOKEN="{{ .AliCloudSecurityToken }}"
-export ALICLOUD_ACCESS_KEY="{{ .AliCloudAccessKey }}"
-export ALICLOUD_SECRET_KEY="{{ .AliCloudSecretKey }}"
-export ALICLOUD_SECURITY_TOKEN="{{ .AliCloudSecurityToken }}"
-export ALICLOUD_PROFILE="{{ .ProfileName }}"
-export SAML2ALIBABA_CLOUD_PROFILE="{{ .ProfileName }}"
-`
-
-const fishTmpl = `set -gx ALIBABA_CLOUD_ACCESS_KEY_ID {{ .AliCloudAccessKey }}
-set -gx ALIBABA_CLOUD_ACCESS_KEY_SECRET {{ .AliCloudSecretKey }}
-set -gx ALIBABA_CLOUD_SESSION_TOKEN {{ .AliCloudSessionToken }}
-set -gx ALIBABA_CLOUD_SECURITY_TOKEN {{ .AliCloudSecurityToken }}
-set -gx ALICLOUD_ACCESS_KEY {{ .AliCloudAccessKey }}
-set -gx ALICLOUD_SECRET_KEY {{ .AliCloudSecretKey }}
-set -gx ALICLOUD_SECURITY_TOKEN {{ .AliCloudSecurityToken }}
-set -gx ALICLOUD_PROFILE {{ .ProfileName }}
-set -gx SAML2ALIBABA_CLOUD_PROFILE {{ .ProfileName }}
-`
-
-const powershellTmpl = `$env:ALIBABA_CLOUD_ACCESS_KEY_ID='{{ .AliCloudAccessKey }}'
-$env:ALIBABA_CLOUD_ACCESS_KEY_SECRET='{{ .AliCloudSecretKey }}'
-$env:ALIBABA_CLOUD_SESSION_TOKEN='{{ .AliCloudSessionToken }}'
-$env:ALIBABA_CLOUD_SECURITY_TOKEN='{{ .AliCloudSecurityToken }}'
-$env:ALICLOUD_ACCESS_KEY='{{ .AliCloudAccessKey }}'
-$env:ALICLOUD_SECRET_KEY='{{ .AliCloudSecretKey }}'
-$env:ALICLOUD_SECURITY_TOKEN='{{ .AliCloudSecurityToken }}'
-$env:ALICLOUD_PROFILE='{{ .ProfileName }}'
-$env:SAML2ALIBABA_CLOUD_PROFILE='{{ .ProfileName }}'
-`
-
-// Script will emit a bash script that will export environment variables
-func Script(execFlags *flags.LoginExecFlags, shell string) error {
-	account, err := buildIdpAccount(execFlags)
+const bashTmpl = `{{ range . }}export {{ .Name }}="{{ dquote .Value }}"
+{{ end }}`
+
+const zshTmpl = `{{ range . }}export {{ .Name }}={{ squote .Value }}
+{{ end }}`
+
+const fishTmpl = `{{ range . }}set -gx {{ .Name }} {{ fishquote .Value }}
+{{ end }}`
+
+const powershellTmpl = `{{ range . }}$env:{{ .Name }}={{ psquote .Value }}
+{{ end }}`
+
+const cmdTmpl = `{{ range . }}set "{{ .Name }}={{ cmdescape .Value }}"
+{{ end }}`
+
+const nushellTmpl = `{{ range . }}$env.{{ .Name }} = {{ nuquote .Value }}
+{{ end }}`
+
+const elvishTmpl = `{{ range . }}set-env {{ .Name }} {{ psquote .Value }}
+{{ end }}`
+
+const xonshTmpl = `{{ range . }}${{ .Name }} = {{ pyquote .Value }}
+{{ end }}`
+
+const dotenvTmpl = `{{ range . }}{{ .Name }}={{ dotenvquote .Value }}
+{{ end }}`
+
+const direnvTmpl = `# generated by saml2alibabacloud, run it again once the credentials expire
+{{ range . }}export {{ .Name }}={{ squote .Value }}
+{{ end }}`
+
+var scriptTmpls = map[string]string{
+	"bash":       bashTmpl,
+	"zsh":        zshTmpl,
+	"fish":       fishTmpl,
+	"powershell": powershellTmpl,
+	"cmd":        cmdTmpl,
+	"nushell":    nushellTmpl,
+	"elvish":     elvishTmpl,
+	"xonsh":      xonshTmpl,
+	"dotenv":     dotenvTmpl,
+	"direnv":     direnvTmpl,
+}
+
+const unsetTmpl = `{{ range . }}unset {{ .Name }}
+{{ end }}`
+
+var unsetTmpls = map[string]string{
+	"bash":       unsetTmpl,
+	"zsh":        unsetTmpl,
+	"direnv":     unsetTmpl,
+	"fish":       "{{ range . }}set -e {{ .Name }}\n{{ end }}",
+	"powershell": "{{ range . }}Remove-Item Env:{{ .Name }} -ErrorAction SilentlyContinue\n{{ end }}",
+	"cmd":        "{{ range . }}set {{ .Name }}=\n{{ end }}",
+	"nushell":    "{{ range . }}hide-env -i {{ .Name }}\n{{ end }}",
+	"elvish":     "{{ range . }}unset-env {{ .Name }}\n{{ end }}",
+	"xonsh":      "{{ range . }}del ${{ .Name }}\n{{ end }}",
+}
+
+// ScriptShells the shells the script command emits a script for
+var ScriptShells = []string{"bash", "zsh", "fish", "powershell", "cmd", "nushell", "elvish", "xonsh", "dotenv", "direnv"}
+
+var scriptFuncs = template.FuncMap{
+	// inside double quotes for sh, a newline can't be escaped there
+	"dquote": func(value string) (string, error) {
+		if err := refuseNewline(value); err != nil {
+			return "", err
+		}
+		return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(value), nil
+	},
+	// single quotes for sh, a quote closes the string, is escaped and the string reopened
+	"squote": func(value string) (string, error) {
+		if err := refuseNewline(value); err != nil {
+			return "", err
+		}
+		return "'" + strings.Replace(value, "'", `'\''`, -1) + "'", nil
+	},
+	// double quotes for dotenv files, docker compose interpolates $ unless it is doubled
+	"dotenvquote": func(value string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", "$$", "\n", `\n`).Replace(value) + `"`
+	},
+	// single quotes for fish which only escape the quote and backslash
+	"fishquote": func(value string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(value) + "'"
+	},
+	// single quotes for powershell and elvish which escape a quote by doubling it
+	"psquote": func(value string) string {
+		return "'" + strings.Replace(value, "'", "''", -1) + "'"
+	},
+	// inside the quoted set of cmd which keeps & | < > literal, only the variable expansion needs escaping,
+	// %% only stands for % in a batch file so the script has to be run as one
+	"cmdescape": strings.NewReplacer("%", "%%", "\n", " ").Replace,
+	// double quotes for nushell
+	"nuquote": func(value string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+	},
+	// a python string for xonsh
+	"pyquote": func(value string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`, "\n", `\n`).Replace(value) + "'"
+	},
+}
+
+// Script will emit a script for the shell that will export environment variables, or unset them with --unset,
+// the saved credentials are printed as is so a role_chain is only applied by an earlier login
+func Script(scriptFlags *flags.ScriptFlags) error {
+	if scriptFlags.Unset {
+		// only the names matter when unsetting so there is no need for credentials
+		data := shell.BuildScriptVars(&alibabacloudconfig.AliCloudCredentials{}, "")
+
+		err := writeScript(os.Stdout, scriptFlags.Shell, true, data)
+		if err != nil {
+			return errors.Wrap(err, "error generating template")
+		}
+		return nil
+	}
+
+	account, err := buildIdpAccount(scriptFlags.LoginExecFlags)
 	if err != nil {
 		return errors.Wrap(err, "error building login details")
 	}
@@ -69,16 +154,16 @@ func Script(execFlags *flags.LoginExecFlags, shell string) error {
 		return errors.Wrap(err, "error loading credentials")
 	}
 
-	// annoymous struct to pass to template
-	data := struct {
-		ProfileName string
-		*alibabacloudconfig.AliCloudCredentials
-	}{
-		account.Profile,
-		alibabacloudCreds,
+	// the script is evaluated by the shell so there is no opportunity to prompt for a login here, profiles
+	// saved without an expiry are printed as they were before the expiry was recorded
+	if !alibabacloudCreds.Expires.IsZero() && time.Now().After(alibabacloudCreds.Expires) {
+		return errors.Errorf("credentials for profile %s have expired, run saml2alibabacloud login to refresh them", account.Profile)
 	}
 
-	err = buildTmpl(shell, data)
+	data := shell.BuildScriptVars(alibabacloudCreds, account.Profile)
+
+	// this is still written to stdout as per convention
+	err = writeScript(os.Stdout, scriptFlags.Shell, false, data)
 	if err != nil {
 		return errors.Wrap(err, "error generating template")
 	}
@@ -86,23 +171,33 @@ func Script(execFlags *flags.LoginExecFlags, shell string) error {
 	return nil
 }
 
-func buildTmpl(shell string, data interface{}) error {
-	t := template.New("envvar_script")
+// refuseNewline fail on a value with a newline, the sh scripts are evaluated with eval $(...) which
+// splits it into words
+func refuseNewline(value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return errors.New("values with a newline can't be exported for this shell")
+	}
+	return nil
+}
 
-	var err error
+func writeScript(w io.Writer, shellType string, unset bool, data []shell.ScriptVar) error {
+	tmpls := scriptTmpls
+	if unset {
+		tmpls = unsetTmpls
+	}
 
-	switch shell {
-	case "bash":
-		t, err = t.Parse(bashTmpl)
-	case "powershell":
-		t, err = t.Parse(powershellTmpl)
-	case "fish":
-		t, err = t.Parse(fishTmpl)
+	tmpl, ok := tmpls[shellType]
+	if !ok {
+		if unset {
+			return errors.Errorf("--unset isn't supported for %s", shellType)
+		}
+		return errors.Errorf("unsupported shell %s", shellType)
 	}
 
+	t, err := template.New("envvar_script").Funcs(scriptFuncs).Parse(tmpl)
 	if err != nil {
 		return err
 	}
-	// this is still written to stdout as per convention
-	return t.Execute(os.Stdout, data)
+
+	return t.Execute(w, data)
 }
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteScript(t *testing.T) {
+	data := []shell.ScriptVar{{Name: "TOKEN", Value: `a'b"c$d\e%f`}}
+
+	tests := []struct {
+		shell string
+		want  string
+	}{
+		{"bash", `export TOKEN="a'b\"c\$d\\e%f"` + "\n"},
+		{"zsh", `export TOKEN='a'\''b"c$d\e%f'` + "\n"},
+		{"fish", `set -gx TOKEN 'a\'b"c$d\\e%f'` + "\n"},
+		{"powershell", `$env:TOKEN='a''b"c$d\e%f'` + "\n"},
+		{"cmd", `set "TOKEN=a'b"c$d\e%%f"` + "\n"},
+		{"nushell", `$env.TOKEN = "a'b\"c$d\\e%f"` + "\n"},
+		{"elvish", `set-env TOKEN 'a''b"c$d\e%f'` + "\n"},
+		{"xonsh", `$TOKEN = 'a\'b"c$d\\e%f'` + "\n"},
+		{"dotenv", `TOKEN="a'b\"c$$d\\e%f"` + "\n"},
+		{"direnv", "# generated by saml2alibabacloud, run it again once the credentials expire\n" + `export TOKEN='a'\''b"c$d\e%f'` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			require.Nil(t, writeScript(buf, tt.shell, false, data))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestWriteScriptNewline(t *testing.T) {
+	data := []shell.ScriptVar{{Name: "TOKEN", Value: "a\nb"}}
+
+	for _, sh := range []string{"bash", "zsh", "direnv"} {
+		err := writeScript(new(bytes.Buffer), sh, false, data)
+		assert.Error(t, err, sh)
+	}
+
+	buf := new(bytes.Buffer)
+	require.Nil(t, writeScript(buf, "dotenv", false, data))
+	assert.Equal(t, `TOKEN="a\nb"`+"\n", buf.String())
+}
+
+func TestWriteScriptUnset(t *testing.T) {
+	data := []shell.ScriptVar{{Name: "TOKEN"}, {Name: "PROFILE"}}
+
+	buf := new(bytes.Buffer)
+	require.Nil(t, writeScript(buf, "fish", true, data))
+	assert.Equal(t, "set -e TOKEN\nset -e PROFILE\n", buf.String())
+
+	buf.Reset()
+	require.Nil(t, writeScript(buf, "bash", true, data))
+	assert.Equal(t, "unset TOKEN\nunset PROFILE\n", buf.String())
+
+	err := writeScript(buf, "dotenv", true, data)
+	assert.EqualError(t, err, "--unset isn't supported for dotenv")
+}
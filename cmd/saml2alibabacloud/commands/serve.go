@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/aliyun/saml2alibabacloud/pkg/credserver"
+	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/pkg/errors"
+)
+
+// refreshWindow credentials are refreshed this long before they expire so clients never receive expired ones
+const refreshWindow = 5 * time.Minute
+
+// Serve serve the credentials of the profile through an emulation of the ECS instance metadata or,
+// with --uri, through an endpoint for the credentials URI provider of the SDKs
+func Serve(serveFlags *flags.ServeFlags) error {
+	account, err := buildIdpAccount(serveFlags.LoginExecFlags)
+	if err != nil {
+		return errors.Wrap(err, "error building login details")
+	}
+
+	refresher, err := newCredentialsRefresher(account, serveFlags.LoginExecFlags)
+	if err != nil {
+		return err
+	}
+
+	// login up front so the prompts aren't triggered by the first request
+	if _, err := refresher.Get(); err != nil {
+		return err
+	}
+	go refresher.keepFresh()
+
+	if serveFlags.URI {
+		// the credentials URI is never served without a token, a random one is generated when none is supplied
+		token := serveFlags.Token
+		if token == "" {
+			token, err = credserver.NewToken()
+			if err != nil {
+				return err
+			}
+		}
+
+		log.Printf("Serving credentials for profile %s on http://%s/?token=%s", account.Profile, serveFlags.Address, token)
+		return http.ListenAndServe(serveFlags.Address, credserver.NewURIHandler(refresher.Get, token))
+	}
+
+	log.Printf("Serving ECS metadata credentials for profile %s on http://%s%s", account.Profile, serveFlags.Address, credserver.SecurityCredentialsPath)
+
+	return http.ListenAndServe(serveFlags.Address, credserver.NewECSMetadataHandler(refresher.Get))
+}
+
+// startCredentialsURI serve the credentials on a random local port protected by a random token, the
+// returned URI is meant for ALIBABA_CLOUD_CREDENTIALS_URI
+func startCredentialsURI(refresher *credentialsRefresher) (string, func(), error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, errors.Wrap(err, "error starting credentials URI server")
+	}
+
+	token, err := credserver.NewToken()
+	if err != nil {
+		listener.Close()
+		return "", nil, err
+	}
+
+	server := &http.Server{Handler: credserver.NewURIHandler(refresher.Get, token)}
+	go server.Serve(listener)
+
+	uri := fmt.Sprintf("http://%s/?token=%s", listener.Addr().String(), token)
+
+	return uri, func() { server.Close() }, nil
+}
+
+// newCredentialsRefresher refresh the profile of the account, the login of role_profiles saves to other profiles
+// so it would never refresh the one served
+func newCredentialsRefresher(account *cfg.IDPAccount, loginFlags *flags.LoginExecFlags) (*credentialsRefresher, error) {
+	if len(account.RoleProfiles) > 0 {
+		return nil, errors.New("credentials can't be refreshed for ROLE=PROFILE roles, select a single role")
+	}
+
+	return &credentialsRefresher{
+		account:     account,
+		sharedCreds: alibabacloudconfig.NewSharedCredentials(account.Profile),
+		login: func() error {
+			// the saved credentials are about to expire so login must not skip over them
+			loginFlags.Force = true
+			return Login(loginFlags)
+		},
+	}, nil
+}
+
+// credentialsRefresher hands out the saved credentials, logging in again when they are about to expire
+type credentialsRefresher struct {
+	account     *cfg.IDPAccount
+	sharedCreds *alibabacloudconfig.CredentialsProvider
+	login       func() error
+
+	mu    sync.Mutex
+	creds *alibabacloudconfig.AliCloudCredentials
+}
+
+// Get return credentials which are valid for at least the refresh window
+func (r *credentialsRefresher) Get() (*alibabacloudconfig.AliCloudCredentials, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.creds != nil && fresh(r.creds) {
+		return r.creds, nil
+	}
+
+	// another process may have logged in already, possibly with another role or policy
+	creds, err := r.sharedCreds.Load()
+	if err == nil && fresh(creds) && cachedCredentialsMatch(r.account, creds, r.account.RoleARN) {
+		r.creds = creds
+		return creds, nil
+	}
+
+	log.Println("credentials about to expire triggering login")
+	if err := r.login(); err != nil {
+		return nil, errors.Wrap(err, "error logging in")
+	}
+
+	creds, err = r.sharedCreds.Load()
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading credentials")
+	}
+	r.creds = creds
+
+	return creds, nil
+}
+
+// keepFresh refresh the credentials ahead of their expiry instead of waiting for a request to need them
+func (r *credentialsRefresher) keepFresh() {
+	for {
+		r.mu.Lock()
+		wait := time.Minute
+		if r.creds != nil {
+			wait = time.Until(r.creds.Expires.Add(-refreshWindow))
+		}
+		r.mu.Unlock()
+
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+
+		if _, err := r.Get(); err != nil {
+			log.Printf("unable to refresh credentials: %v", err)
+			time.Sleep(time.Minute)
+		}
+	}
+}
+
+func fresh(creds *alibabacloudconfig.AliCloudCredentials) bool {
+	return !creds.Expires.IsZero() && time.Now().Add(refreshWindow).Before(creds.Expires)
+}
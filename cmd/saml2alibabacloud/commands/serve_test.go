@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialsRefresher(t *testing.T) {
+	_, cleanup := tempHome(t)
+	defer cleanup()
+
+	sharedCreds := alibabacloudconfig.NewSharedCredentials("saml")
+
+	// expires within the refresh window so it has to be refreshed
+	err := sharedCreds.Save(&alibabacloudconfig.AliCloudCredentials{
+		AliCloudAccessKey:     "STS.old",
+		AliCloudSecretKey:     "secret",
+		AliCloudSecurityToken: "token",
+		Expires:               time.Now().Add(2 * time.Minute),
+	})
+	require.Nil(t, err)
+
+	logins := 0
+	refresher := &credentialsRefresher{
+		account:     &cfg.IDPAccount{},
+		sharedCreds: sharedCreds,
+		login: func() error {
+			logins++
+			return sharedCreds.Save(&alibabacloudconfig.AliCloudCredentials{
+				AliCloudAccessKey:     "STS.new",
+				AliCloudSecretKey:     "secret",
+				AliCloudSecurityToken: "token",
+				Expires:               time.Now().Add(time.Hour),
+			})
+		},
+	}
+
+	creds, err := refresher.Get()
+	require.Nil(t, err)
+	assert.Equal(t, "STS.new", creds.AliCloudAccessKey)
+	assert.Equal(t, 1, logins)
+
+	// the fresh credentials are reused
+	creds, err = refresher.Get()
+	require.Nil(t, err)
+	assert.Equal(t, "STS.new", creds.AliCloudAccessKey)
+	assert.Equal(t, 1, logins)
+}
+
+func TestNewCredentialsRefresherRoleProfiles(t *testing.T) {
+	account := cfg.NewIDPAccount()
+	account.RoleProfiles = []string{"dev-admin=dev", "prod-admin=prod"}
+
+	_, err := newCredentialsRefresher(account, &flags.LoginExecFlags{})
+	assert.Error(t, err)
+
+	account.RoleProfiles = nil
+	refresher, err := newCredentialsRefresher(account, &flags.LoginExecFlags{})
+	require.Nil(t, err)
+	assert.Equal(t, account.Profile, refresher.sharedCreds.Profile)
+}
+
+func TestStartCredentialsURI(t *testing.T) {
+	refresher := &credentialsRefresher{
+		creds: &alibabacloudconfig.AliCloudCredentials{
+			AliCloudAccessKey:     "STS.key",
+			AliCloudSecretKey:     "secret",
+			AliCloudSecurityToken: "token",
+			Expires:               time.Now().Add(time.Hour),
+		},
+	}
+
+	uri, stop, err := startCredentialsURI(refresher)
+	require.Nil(t, err)
+	defer stop()
+
+	resp, err := http.Get(uri)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Contains(t, string(body), `"AccessKeyId":"STS.key"`)
+
+	// the token is required
+	resp, err = http.Get(strings.Split(uri, "?")[0])
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	saml2alibabacloud "github.com/aliyun/saml2alibabacloud"
+	"github.com/aliyun/saml2alibabacloud/helper/credentials"
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/pkg/errors"
+)
+
+// statusEntry the state of a profile saved for an idp account
+type statusEntry struct {
+	IDPAccount       string     `json:"idp_account"`
+	Profile          string     `json:"profile"`
+	LoggedIn         bool       `json:"logged_in"`
+	RoleARN          string     `json:"role_arn,omitempty"`
+	AccountID        string     `json:"account_id,omitempty"`
+	AccountAlias     string     `json:"account_alias,omitempty"`
+	Region           string     `json:"region,omitempty"`
+	Expiration       *time.Time `json:"expiration,omitempty"`
+	RemainingSeconds int64      `json:"remaining_seconds"`
+	PasswordStored   *bool      `json:"password_stored,omitempty"`
+	Verified         *bool      `json:"verified,omitempty"`
+}
+
+// Status show the profile, role, account, region and remaining time of every configured idp account, the expiry
+// comes from the saved metadata and the credentials are only checked against STS with --verify
+func Status(statusFlags *flags.StatusFlags) error {
+	cfgm, err := cfg.NewConfigManager(statusFlags.CommonFlags.ConfigFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load configuration")
+	}
+
+	names, err := cfgm.IDPAccountNames()
+	if err != nil {
+		return errors.Wrap(err, "failed to load idp accounts")
+	}
+
+	now := time.Now()
+	entries := []*statusEntry{}
+	for _, name := range names {
+		account, err := cfgm.LoadIDPAccount(name)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load idp account %s", name)
+		}
+		account.Name = name
+
+		profiles, err := accountProfiles(account.Profile, account.RoleProfiles)
+		if err != nil {
+			return errors.Wrapf(err, "invalid role_profiles for idp account %s", name)
+		}
+
+		passwordStored := accountPasswordStored(account, statusFlags.CommonFlags.DisableKeychain)
+
+		for _, profile := range profiles {
+			entry := buildStatusEntry(account, profile, now)
+			entry.PasswordStored = passwordStored
+
+			if statusFlags.Verify && entry.LoggedIn {
+				entry.Verified = verifyProfile(account, profile)
+			}
+
+			entries = append(entries, entry)
+		}
+	}
+
+	return writeStatus(os.Stdout, statusFlags.Output, entries)
+}
+
+// accountPasswordStored tell whether the keychain holds a password for the account without reading it, which
+// would trigger the access prompt of the macOS keychain, nil when it is unknown
+func accountPasswordStored(account *cfg.IDPAccount, disableKeychain bool) *bool {
+	if disableKeychain {
+		return nil
+	}
+
+	stored := false
+	if account.URL == "" || !credentials.SupportsStorage() {
+		return &stored
+	}
+
+	stored, err := credentials.HasCredentials(account.URL)
+	if err != nil {
+		if err != credentials.ErrListNotSupported {
+			log.Printf("unable to check the keychain for idp account %s: %v", account.Name, err)
+		}
+		return nil
+	}
+
+	return &stored
+}
+
+func buildStatusEntry(account *cfg.IDPAccount, profile string, now time.Time) *statusEntry {
+	entry := &statusEntry{IDPAccount: account.Name, Profile: profile}
+
+	alibabacloudCreds, err := alibabacloudconfig.NewSharedCredentials(profile).Load()
+	if err != nil || alibabacloudCreds.AliCloudAccessKey == "" {
+		return entry
+	}
+
+	role := &saml2alibabacloud.RamRole{RoleARN: alibabacloudCreds.PrincipalARN}
+
+	entry.LoggedIn = true
+	entry.RoleARN = alibabacloudCreds.PrincipalARN
+	entry.AccountID = role.AccountID()
+	entry.AccountAlias = account.AccountAliases[entry.AccountID]
+	entry.Region = alibabacloudCreds.Region
+	if !alibabacloudCreds.Expires.IsZero() {
+		expires := alibabacloudCreds.Expires
+		entry.Expiration = &expires
+		if remaining := expires.Sub(now); remaining > 0 {
+			entry.RemainingSeconds = int64(remaining.Seconds())
+		}
+	}
+
+	return entry
+}
+
+// verifyProfile call GetCallerIdentity with the saved credentials
+func verifyProfile(account *cfg.IDPAccount, profile string) *bool {
+	valid := false
+
+	alibabacloudCreds, err := alibabacloudconfig.NewSharedCredentials(profile).Load()
+	if err == nil {
+		valid, err = checkToken(account, alibabacloudCreds)
+	}
+	if err != nil {
+		log.Printf("unable to verify profile %s: %v", profile, err)
+	}
+
+	return &valid
+}
+
+func writeStatus(w io.Writer, format string, entries []*statusEntry) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join([]string{"IDP ACCOUNT", "PROFILE", "ACCOUNT", "ROLE", "REGION", "EXPIRES IN", "PASSWORD", "VERIFIED"}, "\t"))
+		for _, entry := range entries {
+			fmt.Fprintln(tw, strings.Join(entry.fields(), "\t"))
+		}
+		return tw.Flush()
+	default:
+		return errors.Errorf("unsupported output format: %s", format)
+	}
+}
+
+func (e *statusEntry) fields() []string {
+	account, expiresIn := "-", "-"
+	if e.LoggedIn {
+		account = e.AccountID
+		if e.AccountAlias != "" {
+			account = e.AccountAlias
+		}
+		// credentials saved without an expiry can't tell the time left
+		if e.Expiration != nil {
+			expiresIn = formatRemaining(time.Duration(e.RemainingSeconds) * time.Second)
+		}
+	}
+
+	return []string{
+		e.IDPAccount,
+		e.Profile,
+		account,
+		dash(e.RoleARN),
+		dash(e.Region),
+		expiresIn,
+		yesNo(e.PasswordStored),
+		yesNo(e.Verified),
+	}
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func yesNo(value *bool) string {
+	switch {
+	case value == nil:
+		return "-"
+	case *value:
+		return "yes"
+	default:
+		return "no"
+	}
+}
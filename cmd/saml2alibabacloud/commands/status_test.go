@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aliyun/saml2alibabacloud/helper/credentials"
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildStatusEntry(t *testing.T) {
+	_, cleanup := tempHome(t)
+	defer cleanup()
+
+	now := time.Now()
+	err := alibabacloudconfig.NewSharedCredentials("saml").Save(&alibabacloudconfig.AliCloudCredentials{
+		AliCloudAccessKey: "STS.key",
+		PrincipalARN:      "acs:ram::000000000001:assumed-role/admin/alice",
+		Region:            "ap-southeast-1",
+		Expires:           now.Add(time.Hour),
+	})
+	require.Nil(t, err)
+
+	account := &cfg.IDPAccount{
+		Name:           "work",
+		AccountAliases: map[string]string{"000000000001": "production"},
+	}
+
+	entry := buildStatusEntry(account, "saml", now)
+	assert.True(t, entry.LoggedIn)
+	assert.Equal(t, "acs:ram::000000000001:assumed-role/admin/alice", entry.RoleARN)
+	assert.Equal(t, "000000000001", entry.AccountID)
+	assert.Equal(t, "production", entry.AccountAlias)
+	assert.Equal(t, "ap-southeast-1", entry.Region)
+	assert.Equal(t, int64(3600), entry.RemainingSeconds)
+
+	entry = buildStatusEntry(account, "missing", now)
+	assert.Equal(t, &statusEntry{IDPAccount: "work", Profile: "missing"}, entry)
+}
+
+func TestWriteStatus(t *testing.T) {
+	stored, missing := true, false
+	expires := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := []*statusEntry{
+		{
+			IDPAccount:       "work",
+			Profile:          "saml",
+			LoggedIn:         true,
+			RoleARN:          "acs:ram::000000000001:role/admin",
+			AccountID:        "000000000001",
+			AccountAlias:     "production",
+			Region:           "cn-hangzhou",
+			Expiration:       &expires,
+			RemainingSeconds: 3540,
+			PasswordStored:   &stored,
+		},
+		{IDPAccount: "personal", Profile: "personal", PasswordStored: &missing},
+		{IDPAccount: "legacy", Profile: "legacy", LoggedIn: true, AccountID: "000000000002", AccountAlias: "staging", RoleARN: "acs:ram::000000000002:role/admin"},
+	}
+
+	buf := new(bytes.Buffer)
+	require.Nil(t, writeStatus(buf, "table", entries))
+	assert.Equal(t, `IDP ACCOUNT  PROFILE   ACCOUNT     ROLE                              REGION       EXPIRES IN  PASSWORD  VERIFIED
+work         saml      production  acs:ram::000000000001:role/admin  cn-hangzhou  59m         yes       -
+personal     personal  -           -                                 -            -           no        -
+legacy       legacy    staging     acs:ram::000000000002:role/admin  -            -           -         -
+`, buf.String())
+
+	buf.Reset()
+	require.Nil(t, writeStatus(buf, "json", entries[1:2]))
+	assert.JSONEq(t, `[{"idp_account":"personal","profile":"personal","logged_in":false,"remaining_seconds":0,"password_stored":false}]`, buf.String())
+
+	assert.NotNil(t, writeStatus(buf, "yaml", entries))
+}
+
+func TestAccountPasswordStored(t *testing.T) {
+	defer func(helper credentials.Helper) { credentials.CurrentHelper = helper }(credentials.CurrentHelper)
+	credentials.CurrentHelper = &listingHelper{t: t, stored: map[string]string{"https://id.example.com": "alice"}}
+
+	assert.Equal(t, true, *accountPasswordStored(&cfg.IDPAccount{URL: "https://id.example.com"}, false))
+	assert.Equal(t, false, *accountPasswordStored(&cfg.IDPAccount{URL: "https://other.example.com"}, false))
+	assert.Nil(t, accountPasswordStored(&cfg.IDPAccount{URL: "https://id.example.com"}, true))
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/sts"
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/pkg/errors"
+)
+
+// defaultSTSRegion the region used for STS calls when neither sts_region nor region are configured
+const defaultSTSRegion = "cn-hangzhou"
+
+// newSTSClient build an STS client for the endpoint and region configured on the idp account, without
+// credentials the client can only be used for AssumeRoleWithSAML which does not require signing
+func newSTSClient(account *cfg.IDPAccount, alibabacloudCreds *alibabacloudconfig.AliCloudCredentials) (*sts.Client, error) {
+	region := stsRegion(account)
+
+	var client *sts.Client
+	var err error
+	if alibabacloudCreds == nil {
+		client, err = sts.NewClientWithAccessKey(region, "saml2alibabacloud", "0.0.5")
+	} else {
+		client, err = sts.NewClientWithStsToken(region, alibabacloudCreds.AliCloudAccessKey, alibabacloudCreds.AliCloudSecretKey, alibabacloudCreds.AliCloudSecurityToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+	client.AppendUserAgent("saml2alibabacloud", "0.0.5")
+
+	scheme, domain, err := parseSTSEndpoint(account.STSEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	client.GetConfig().WithScheme(scheme)
+	client.Domain = domain
+
+	return client, nil
+}
+
+// stsRegion resolve the region used for STS calls
+func stsRegion(account *cfg.IDPAccount) string {
+	if account.STSRegion != "" {
+		return account.STSRegion
+	}
+	if account.Region != "" {
+		return account.Region
+	}
+	return defaultSTSRegion
+}
+
+// parseSTSEndpoint split the endpoint into the scheme and domain used by the SDK, an endpoint without
+// a scheme uses https and an empty endpoint leaves the SDK to resolve the domain from the region
+func parseSTSEndpoint(endpoint string) (string, string, error) {
+	if endpoint == "" {
+		return "HTTPS", "", nil
+	}
+
+	if !strings.Contains(endpoint, "://") {
+		return "HTTPS", endpoint, nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", errors.Wrap(err, "error parsing STS endpoint")
+	}
+	if u.Host == "" {
+		return "", "", errors.Errorf("invalid STS endpoint: %s", endpoint)
+	}
+
+	return strings.ToUpper(u.Scheme), u.Host, nil
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	b64 "encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	saml2alibabacloud "github.com/aliyun/saml2alibabacloud"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStsRegion(t *testing.T) {
+
+	idpa := cfg.NewIDPAccount()
+	assert.Equal(t, "cn-hangzhou", stsRegion(idpa))
+
+	idpa.Region = "ap-southeast-1"
+	assert.Equal(t, "ap-southeast-1", stsRegion(idpa))
+
+	idpa.STSRegion = "eu-central-1"
+	assert.Equal(t, "eu-central-1", stsRegion(idpa))
+}
+
+func TestParseSTSEndpoint(t *testing.T) {
+
+	tests := []struct {
+		endpoint string
+		scheme   string
+		domain   string
+	}{
+		{endpoint: "", scheme: "HTTPS", domain: ""},
+		{endpoint: "sts.ap-southeast-1.aliyuncs.com", scheme: "HTTPS", domain: "sts.ap-southeast-1.aliyuncs.com"},
+		{endpoint: "http://localhost:8080", scheme: "HTTP", domain: "localhost:8080"},
+	}
+	for _, tt := range tests {
+		scheme, domain, err := parseSTSEndpoint(tt.endpoint)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.scheme, scheme, tt.endpoint)
+		assert.Equal(t, tt.domain, domain, tt.endpoint)
+	}
+}
+
+func TestLoginToStsUsingRoleEndpoint(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "AssumeRoleWithSAML", r.URL.Query().Get("Action"))
+		assert.Equal(t, "3600", r.URL.Query().Get("DurationSeconds"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"RequestId": "6894B13B-6D71-4EF5-88FA-F32781734A7F",
+			"Credentials": {
+				"AccessKeyId": "STS.L4aBSCSJVMuKg5U1vFDw",
+				"AccessKeySecret": "wyLTSmsyPGP1ohvvw8xYgB29dlGI8KMiH2pKCNZ9",
+				"SecurityToken": "CAESrAIIARKAAShQquMnLIlbvEcIxO6wCoqJufs8sWwieUxu45hS9AvKNEte8KRUWiJWJ6Y+YHAPgNwi7yfRecMFydL2uPOgBI7LDio0RkbYLmJfIxHM2nGBPdml7kYEOXmJp2aDhbvvwVYIyt/8iES/R6N208wQh0Pk2bu+/9dvalp6wOHF4gkFGhhTVFMuTDRhQlNDU0pWTXVLZzVVMXZGRHciBTQzMjc0KgVhbGljZTCpnJjwySk6BlJzYU1ENUJuCgExGmkKBUFsbG93Eh8KDEFjdGlvbkVxdWFscxIGQWN0aW9uGgcKBW9zczoqEj8KDlJlc291cmNlRXF1YWxzEghSZXNvdXJjZRojCiFhY3M6b3NzOio6NDMyNzQ6c2FtcGxlYm94L2FsaWNlLyo=",
+				"Expiration": "2015-04-09T11:52:19Z"
+			},
+			"AssumedRoleUser": {
+				"AssumedRoleId": "344584339364951186:alice",
+				"Arn": "acs:ram::123123123123:assumed-role/Ali-CloudAdminOps-Build/alice"
+			}
+		}`)
+	}))
+	defer ts.Close()
+
+	data, err := ioutil.ReadFile("../../../testdata/assertion.xml")
+	require.Nil(t, err)
+
+	idpa := cfg.NewIDPAccount()
+	idpa.STSEndpoint = ts.URL
+
+	role := &saml2alibabacloud.RamRole{
+		RoleARN:      "acs:ram::123123123123:role/Ali-CloudAdminOps-Build",
+		PrincipalARN: "acs:ram::123123123123:saml-provider/ExampleADFS",
+	}
+
+	alibabacloudCreds, err := loginToStsUsingRole(idpa, role, b64.StdEncoding.EncodeToString(data), cfg.DefaultSessionDuration)
+	require.Nil(t, err)
+	assert.Equal(t, "STS.L4aBSCSJVMuKg5U1vFDw", alibabacloudCreds.AliCloudAccessKey)
+	assert.Equal(t, "acs:ram::123123123123:assumed-role/Ali-CloudAdminOps-Build/alice", alibabacloudCreds.PrincipalARN)
+	assert.Equal(t, int64(1428580339), alibabacloudCreds.Expires.Unix())
+}
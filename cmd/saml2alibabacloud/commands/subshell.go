@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	saml2alibabacloud "github.com/aliyun/saml2alibabacloud"
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/aliyun/saml2alibabacloud/pkg/shell"
+	"github.com/pkg/errors"
+)
+
+const (
+	// activeEnvVar set to the profile inside a shell started by saml2alibabacloud
+	activeEnvVar = "SAML2ALIBABACLOUD_ACTIVE"
+
+	// promptEnvVar holds the account and role for the prompt of the shell, the prompt works out the time left from expirationEnvVar
+	promptEnvVar = "SAML2ALIBABACLOUD_PROMPT"
+
+	// expirationEnvVar holds the expiry of the credentials in UTC so prompts can count down the remaining time
+	expirationEnvVar = "SAML2ALIBABACLOUD_EXPIRATION"
+)
+
+// Shell start the shell of the user with the env vars emitted by script, logging in first when the credentials are expired
+func Shell(shellFlags *flags.ShellFlags) error {
+	if active := os.Getenv(activeEnvVar); active != "" && !shellFlags.ForceNested {
+		return errors.Errorf("already in a saml2alibabacloud shell for profile %s, exit it first or use --force-nested", active)
+	}
+
+	account, err := buildIdpAccount(shellFlags.LoginExecFlags)
+	if err != nil {
+		return errors.Wrap(err, "error building login details")
+	}
+
+	role, err := profileRole(account)
+	if err != nil {
+		return err
+	}
+
+	sharedCreds := alibabacloudconfig.NewSharedCredentials(account.Profile)
+
+	if shellFlags.LoginExecFlags.Force || !cachedCredentialsUsable(account, sharedCreds, role) {
+		err = Login(shellFlags.LoginExecFlags)
+		if err != nil {
+			return errors.Wrap(err, "error logging in")
+		}
+	}
+
+	alibabacloudCreds, err := sharedCreds.Load()
+	if err != nil {
+		return errors.Wrap(err, "error loading credentials")
+	}
+
+	vars := append(shell.BuildScriptVars(alibabacloudCreds, account.Profile),
+		shell.ScriptVar{Name: activeEnvVar, Value: account.Profile},
+		shell.ScriptVar{Name: promptEnvVar, Value: promptHint(account, alibabacloudCreds)},
+		shell.ScriptVar{Name: expirationEnvVar, Value: alibabacloudCreds.Expires.UTC().Format(time.RFC3339)},
+	)
+
+	return shell.ExecCmd([]string{userShell()}, shell.FormatEnvVars(vars))
+}
+
+// promptHint describe the session for the prompt, e.g. production/admin, the time left changes so the prompt
+// works it out from the expiration
+func promptHint(account *cfg.IDPAccount, alibabacloudCreds *alibabacloudconfig.AliCloudCredentials) string {
+	role := &saml2alibabacloud.RamRole{RoleARN: alibabacloudCreds.PrincipalARN}
+
+	name := role.AccountID()
+	if alias, ok := account.AccountAliases[name]; ok {
+		name = alias
+	}
+
+	// an assumed role ARN ends with role/<name>/<session>
+	roleName := strings.SplitN(role.RoleName(), "/", 2)[0]
+
+	return fmt.Sprintf("%s/%s", name, roleName)
+}
+
+// formatRemaining format the time left on the session in minutes, e.g. 1h05m or 59m
+func formatRemaining(remaining time.Duration) string {
+	if remaining <= 0 {
+		return "expired"
+	}
+
+	minutes := int(remaining.Minutes())
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+}
+
+func userShell() string {
+	if runtime.GOOS == "windows" {
+		if comspec := os.Getenv("COMSPEC"); comspec != "" {
+			return comspec
+		}
+		return "cmd"
+	}
+
+	if sh := os.Getenv("SHELL"); sh != "" {
+		return sh
+	}
+	return "/bin/sh"
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromptHint(t *testing.T) {
+	account := &cfg.IDPAccount{
+		AccountAliases: map[string]string{"000000000001": "production"},
+	}
+
+	hint := promptHint(account, &alibabacloudconfig.AliCloudCredentials{
+		PrincipalARN: "acs:ram::000000000001:assumed-role/admin/alice",
+	})
+	assert.Equal(t, "production/admin", hint)
+
+	hint = promptHint(account, &alibabacloudconfig.AliCloudCredentials{
+		PrincipalARN: "acs:ram::000000000002:role/developer",
+	})
+	assert.Equal(t, "000000000002/developer", hint)
+}
+
+func TestFormatRemaining(t *testing.T) {
+	assert.Equal(t, "1h05m", formatRemaining(65*time.Minute+30*time.Second))
+	assert.Equal(t, "59m", formatRemaining(59*time.Minute))
+	assert.Equal(t, "expired", formatRemaining(-time.Minute))
+}
+
+func TestShellRefusesToNest(t *testing.T) {
+	defer os.Setenv(activeEnvVar, os.Getenv(activeEnvVar))
+	os.Setenv(activeEnvVar, "saml")
+
+	err := Shell(&flags.ShellFlags{LoginExecFlags: &flags.LoginExecFlags{CommonFlags: &flags.CommonFlags{}}})
+	assert.EqualError(t, err, "already in a saml2alibabacloud shell for profile saml, exit it first or use --force-nested")
+}
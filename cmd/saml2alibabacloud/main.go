@@ -2,14 +2,19 @@ package main
 
 import (
 	"crypto/tls"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/alecthomas/kingpin"
 	"github.com/aliyun/saml2alibabacloud/cmd/saml2alibabacloud/commands"
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
 	"github.com/aliyun/saml2alibabacloud/pkg/flags"
+	"github.com/aliyun/saml2alibabacloud/pkg/shell"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -42,6 +47,32 @@ func buildCmdList(s kingpin.Settings) (target *[]string) {
 	return
 }
 
+// The `roleList` type makes --role repeatable, a ROLE=PROFILE value adds a role
+// to log in to alongside the others while a plain value, e.g. /env=prod/, selects the single role
+type roleList flags.CommonFlags
+
+func (r *roleList) Set(value string) error {
+	if _, _, ok := cfg.SplitRoleProfile(value); ok {
+		r.RoleProfiles = append(r.RoleProfiles, value)
+		return nil
+	}
+
+	if r.RoleArn != "" {
+		return fmt.Errorf("only one --role without a profile can be supplied, use ROLE=PROFILE to log in to several roles")
+	}
+	r.RoleArn = value
+
+	return nil
+}
+
+func (r *roleList) String() string {
+	return r.RoleArn
+}
+
+func (r *roleList) IsCumulative() bool {
+	return true
+}
+
 func main() {
 
 	log.SetOutput(os.Stderr)
@@ -65,7 +96,7 @@ func main() {
 	// Common (to all commands) settings
 	commonFlags := new(flags.CommonFlags)
 	app.Flag("config", "Path/filename of saml2alibabacloud config file (env: SAML2ALIBABACLOUD_CONFIGFILE)").Envar("SAML2ALIBABACLOUD_CONFIGFILE").StringVar(&commonFlags.ConfigFile)
-	app.Flag("idp-account", "The name of the configured IDP account. (env: SAML2ALIBABACLOUD_IDP_ACCOUNT)").Envar("SAML2ALIBABACLOUD_IDP_ACCOUNT").Short('a').Default("default").StringVar(&commonFlags.IdpAccount)
+	app.Flag("idp-account", "The name of the configured IDP account. (env: SAML2ALIBABACLOUD_IDP_ACCOUNT)").Envar("SAML2ALIBABACLOUD_IDP_ACCOUNT").Short('a').Default(cfg.DefaultIDPAccount).StringVar(&commonFlags.IdpAccount)
 	app.Flag("idp-provider", "The configured IDP provider. (env: SAML2ALIBABACLOUD_IDP_PROVIDER)").Envar("SAML2ALIBABACLOUD_IDP_PROVIDER").EnumVar(&commonFlags.IdpProvider, "Akamai", "AzureAD", "ADFS", "ADFS2", "GoogleApps", "Ping", "JumpCloud", "Okta", "OneLogin", "PSU", "KeyCloak", "F5APM", "Shibboleth", "ShibbolethECP", "NetIQ")
 	app.Flag("mfa", "The name of the mfa. (env: SAML2ALIBABACLOUD_MFA)").Envar("SAML2ALIBABACLOUD_MFA").StringVar(&commonFlags.MFA)
 	app.Flag("skip-verify", "Skip verification of server certificate. (env: SAML2ALIBABACLOUD_SKIP_VERIFY)").Envar("SAML2ALIBABACLOUD_SKIP_VERIFY").Short('s').BoolVar(&commonFlags.SkipVerify)
@@ -73,12 +104,17 @@ func main() {
 	app.Flag("username", "The username used to login. (env: SAML2ALIBABACLOUD_USERNAME)").Envar("SAML2ALIBABACLOUD_USERNAME").StringVar(&commonFlags.Username)
 	app.Flag("password", "The password used to login. (env: SAML2ALIBABACLOUD_PASSWORD)").Envar("SAML2ALIBABACLOUD_PASSWORD").StringVar(&commonFlags.Password)
 	app.Flag("mfa-token", "The current MFA token (supported in Keycloak, ADFS, GoogleApps). (env: SAML2ALIBABACLOUD_MFA_TOKEN)").Envar("SAML2ALIBABACLOUD_MFA_TOKEN").StringVar(&commonFlags.MFAToken)
-	app.Flag("role", "The ARN of the role to assume. (env: SAML2ALIBABACLOUD_ROLE)").Envar("SAML2ALIBABACLOUD_ROLE").StringVar(&commonFlags.RoleArn)
+	app.Flag("role", "The ARN, alias, glob or /regex/ of the role to assume, repeat ROLE=PROFILE to log in to several roles. (env: SAML2ALIBABACLOUD_ROLE)").Envar("SAML2ALIBABACLOUD_ROLE").SetValue((*roleList)(commonFlags))
 	app.Flag("urn", "The URN used by SAML when you login. (env: SAML2ALIBABACLOUD_URN)").Envar("SAML2ALIBABACLOUD_URN").StringVar(&commonFlags.AlibabaCloudURN)
 	app.Flag("skip-prompt", "Skip prompting for parameters during login.").BoolVar(&commonFlags.SkipPrompt)
 	app.Flag("session-duration", "The duration of your AlibabaCloud Session. (env: SAML2ALIBABACLOUD_SESSION_DURATION)").Envar("SAML2ALIBABACLOUD_SESSION_DURATION").IntVar(&commonFlags.SessionDuration)
 	app.Flag("disable-keychain", "Do not use keychain at all.").Envar("SAML2ALIBABACLOUD_DISABLE_KEYCHAIN").BoolVar(&commonFlags.DisableKeychain)
 	app.Flag("region", "AlibabaCloud region to use for API requests, e.g. cn-hangzhou, ap-southeast-1 (env: SAML2ALIBABACLOUD_REGION)").Envar("SAML2ALIBABACLOUD_REGION").Short('r').StringVar(&commonFlags.Region)
+	app.Flag("sts-endpoint", "The STS endpoint to use, e.g. sts.ap-southeast-1.aliyuncs.com or http://localhost:8080 (env: SAML2ALIBABACLOUD_STS_ENDPOINT)").Envar("SAML2ALIBABACLOUD_STS_ENDPOINT").StringVar(&commonFlags.STSEndpoint)
+	app.Flag("sts-region", "The region used for STS requests, defaults to --region or cn-hangzhou (env: SAML2ALIBABACLOUD_STS_REGION)").Envar("SAML2ALIBABACLOUD_STS_REGION").StringVar(&commonFlags.STSRegion)
+	app.Flag("offline-roles", "Build the role list from the role ARNs in the SAML assertion instead of the AlibabaCloud role selection page. (env: SAML2ALIBABACLOUD_OFFLINE_ROLES)").Envar("SAML2ALIBABACLOUD_OFFLINE_ROLES").BoolVar(&commonFlags.OfflineRoles)
+	app.Flag("policy", "An inline JSON policy scoping down the STS session. (env: SAML2ALIBABACLOUD_POLICY)").Envar("SAML2ALIBABACLOUD_POLICY").StringVar(&commonFlags.Policy)
+	app.Flag("policy-file", "A file holding a JSON policy scoping down the STS session. (env: SAML2ALIBABACLOUD_POLICY_FILE)").Envar("SAML2ALIBABACLOUD_POLICY_FILE").StringVar(&commonFlags.PolicyFile)
 
 	// `configure` command and settings
 	cmdConfigure := app.Command("configure", "Configure a new IDP account.")
@@ -90,6 +126,26 @@ func main() {
 	cmdConfigure.Flag("resource-id", "F5APM SAML resource ID of your company account. (env: SAML2ALIBABACLOUD_F5APM_RESOURCE_ID)").Envar("SAML2ALIBABACLOUD_F5APM_RESOURCE_ID").StringVar(&commonFlags.ResourceID)
 	configFlags := commonFlags
 
+	// `config` commands and settings
+	cmdConfig := app.Command("config", "Manage the configured IDP accounts.")
+	configCmdFlags := new(flags.ConfigFlags)
+	configCmdFlags.CommonFlags = commonFlags
+	cmdConfigList := cmdConfig.Command("list", "List the configured IDP accounts.")
+	cmdConfigShow := cmdConfig.Command("show", "Show the settings of an IDP account.")
+	cmdConfigShow.Arg("account", "The name of the IDP account.").Required().StringVar(&configCmdFlags.Account)
+	cmdConfigSet := cmdConfig.Command("set", "Set a setting of an IDP account.")
+	cmdConfigSet.Arg("account", "The name of the IDP account.").Required().StringVar(&configCmdFlags.Account)
+	cmdConfigSet.Arg("key", "The setting to change, e.g. url or alibabacloud_profile.").Required().StringVar(&configCmdFlags.Key)
+	cmdConfigSet.Arg("value", "The new value of the setting.").Required().StringVar(&configCmdFlags.Value)
+	cmdConfigDelete := cmdConfig.Command("delete", "Delete an IDP account.")
+	cmdConfigDelete.Arg("account", "The name of the IDP account.").Required().StringVar(&configCmdFlags.Account)
+	cmdConfigRename := cmdConfig.Command("rename", "Rename an IDP account.")
+	cmdConfigRename.Arg("account", "The name of the IDP account.").Required().StringVar(&configCmdFlags.Account)
+	cmdConfigRename.Arg("new-name", "The new name of the IDP account.").Required().StringVar(&configCmdFlags.NewName)
+	cmdConfigCopy := cmdConfig.Command("copy", "Copy an IDP account to a new one.")
+	cmdConfigCopy.Arg("account", "The name of the IDP account.").Required().StringVar(&configCmdFlags.Account)
+	cmdConfigCopy.Arg("new-name", "The name of the new IDP account.").Required().StringVar(&configCmdFlags.NewName)
+
 	// `login` command and settings
 	cmdLogin := app.Command("login", "Login to a SAML 2.0 IDP and convert the SAML assertion to an STS token.")
 	loginFlags := new(flags.LoginExecFlags)
@@ -99,6 +155,28 @@ func main() {
 	cmdLogin.Flag("client-id", "OneLogin client id, used to generate API access token. (env: ONELOGIN_CLIENT_ID)").Envar("ONELOGIN_CLIENT_ID").StringVar(&commonFlags.ClientID)
 	cmdLogin.Flag("client-secret", "OneLogin client secret, used to generate API access token. (env: ONELOGIN_CLIENT_SECRET)").Envar("ONELOGIN_CLIENT_SECRET").StringVar(&commonFlags.ClientSecret)
 	cmdLogin.Flag("force", "Refresh credentials even if not expired.").BoolVar(&loginFlags.Force)
+	cmdLogin.Flag("write-external-profile", "Also save an External mode AlibabaCloud CLI profile with this name which runs credential-process to refresh the credentials on demand.").StringVar(&loginFlags.ExternalProfile)
+
+	// `logout` command and settings
+	cmdLogout := app.Command("logout", "Remove the AlibabaCloud CLI profiles saved by login.")
+	logoutFlags := new(flags.LogoutFlags)
+	logoutFlags.LoginExecFlags = new(flags.LoginExecFlags)
+	logoutFlags.LoginExecFlags.CommonFlags = commonFlags
+	cmdLogout.Flag("profile", "The AlibabaCloud CLI profile to remove. (env: SAML2ALIBABACLOUD_PROFILE)").Short('p').Envar("SAML2ALIBABACLOUD_PROFILE").StringVar(&commonFlags.Profile)
+	cmdLogout.Flag("all", "Remove every profile saved by saml2alibabacloud.").BoolVar(&logoutFlags.All)
+	cmdLogout.Flag("delete-password", "Also delete the password, and OneLogin client id and secret, stored in the keychain for the IDP account.").BoolVar(&logoutFlags.DeletePassword)
+
+	// `status` command and settings
+	cmdStatus := app.Command("status", "Show the profile, role, account, region and remaining session time of every IDP account.").Alias("whoami")
+	statusFlags := new(flags.StatusFlags)
+	statusFlags.CommonFlags = commonFlags
+	cmdStatus.Flag("output", "Print the status as json or a table.").Short('o').Default("table").EnumVar(&statusFlags.Output, "json", "table")
+	cmdStatus.Flag("verify", "Check the saved credentials with STS GetCallerIdentity.").BoolVar(&statusFlags.Verify)
+
+	// `doctor` command and settings
+	cmdDoctor := app.Command("doctor", "Check the IDP account, the connectivity to the IdP and STS, the keychain and the AlibabaCloud CLI configuration.")
+	doctorFlags := new(flags.LoginExecFlags)
+	doctorFlags.CommonFlags = commonFlags
 
 	// `exec` command and settings
 	cmdExec := app.Command("exec", "Exec the supplied command with env vars from STS token.")
@@ -106,6 +184,9 @@ func main() {
 	execFlags.CommonFlags = commonFlags
 	cmdExec.Flag("profile", "The AlibabaCloud CLI profile to save the temporary credentials. (env: SAML2ALIBABACLOUD_PROFILE)").Envar("SAML2ALIBABACLOUD_PROFILE").Short('p').StringVar(&commonFlags.Profile)
 	cmdExec.Flag("exec-profile", "The AlibabaCloud CLI profile to utilize for command execution. Useful to allow the `aliyun` cli to perform secondary role assumption. (env: SAML2ALIBABACLOUD_EXEC_PROFILE)").Envar("SAML2ALIBABACLOUD_EXEC_PROFILE").StringVar(&execFlags.ExecProfile)
+	cmdExec.Flag("uri", "Serve the credentials on a random local port and pass ALIBABA_CLOUD_CREDENTIALS_URI to the command instead of static keys.").BoolVar(&execFlags.URI)
+	cmdExec.Flag("refresh", "Supervise the command, refreshing the credentials it reads from ALIBABA_CLOUD_CREDENTIALS_URI before they expire and exiting with its exit code.").BoolVar(&execFlags.Refresh)
+	cmdExec.Flag("shell", "Run the command through the default shell, /bin/sh -c or cmd /C, instead of executing it directly.").BoolVar(&execFlags.Shell)
 	cmdLine := buildCmdList(cmdExec.Arg("command", "The command to execute."))
 
 	// `console` command and settings
@@ -120,19 +201,50 @@ func main() {
 
 	// `list` command and settings
 	cmdListRoles := app.Command("list-roles", "List available role ARNs.")
-	listRolesFlags := new(flags.LoginExecFlags)
-	listRolesFlags.CommonFlags = commonFlags
+	listRolesFlags := new(flags.ListRolesFlags)
+	listRolesFlags.LoginExecFlags = new(flags.LoginExecFlags)
+	listRolesFlags.LoginExecFlags.CommonFlags = commonFlags
+	cmdListRoles.Flag("output", "Print the roles as json, csv or a table instead of plain text.").Short('o').EnumVar(&listRolesFlags.Output, "json", "csv", "table")
+
+	// `serve` command and settings
+	cmdServe := app.Command("serve", "Serve the STS credentials through an emulation of the ECS instance metadata, logging in again before they expire.")
+	serveFlags := new(flags.ServeFlags)
+	serveFlags.LoginExecFlags = new(flags.LoginExecFlags)
+	serveFlags.LoginExecFlags.CommonFlags = commonFlags
+	cmdServe.Flag("profile", "The AlibabaCloud CLI profile to save the temporary credentials. (env: SAML2ALIBABACLOUD_PROFILE)").Envar("SAML2ALIBABACLOUD_PROFILE").Short('p').StringVar(&commonFlags.Profile)
+	cmdServe.Flag("address", "The address to listen on. (env: SAML2ALIBABACLOUD_SERVE_ADDRESS)").Envar("SAML2ALIBABACLOUD_SERVE_ADDRESS").Default("127.0.0.1:9911").StringVar(&serveFlags.Address)
+	cmdServe.Flag("uri", "Serve the credentials for ALIBABA_CLOUD_CREDENTIALS_URI instead of emulating the ECS instance metadata.").BoolVar(&serveFlags.URI)
+	cmdServe.Flag("token", "The bearer token (or token query parameter) required to read the credentials with --uri, a random one is generated by default. (env: SAML2ALIBABACLOUD_SERVE_TOKEN)").Envar("SAML2ALIBABACLOUD_SERVE_TOKEN").StringVar(&serveFlags.Token)
+
+	// `shell` command and settings
+	cmdShell := app.Command("shell", "Start your $SHELL with the env vars emitted by script, logging in first if needed.")
+	shellFlags := new(flags.ShellFlags)
+	shellFlags.LoginExecFlags = new(flags.LoginExecFlags)
+	shellFlags.LoginExecFlags.CommonFlags = commonFlags
+	cmdShell.Flag("profile", "The AlibabaCloud CLI profile to save the temporary credentials. (env: SAML2ALIBABACLOUD_PROFILE)").Envar("SAML2ALIBABACLOUD_PROFILE").Short('p').StringVar(&commonFlags.Profile)
+	cmdShell.Flag("duo-mfa-option", "The MFA option you want to use to authenticate with").Envar("SAML2ALIBABACLOUD_DUO_MFA_OPTION").EnumVar(&shellFlags.LoginExecFlags.DuoMFAOption, "Passcode", "Duo Push")
+	cmdShell.Flag("client-id", "OneLogin client id, used to generate API access token. (env: ONELOGIN_CLIENT_ID)").Envar("ONELOGIN_CLIENT_ID").StringVar(&commonFlags.ClientID)
+	cmdShell.Flag("client-secret", "OneLogin client secret, used to generate API access token. (env: ONELOGIN_CLIENT_SECRET)").Envar("ONELOGIN_CLIENT_SECRET").StringVar(&commonFlags.ClientSecret)
+	cmdShell.Flag("force", "Refresh credentials even if not expired.").BoolVar(&shellFlags.LoginExecFlags.Force)
+	cmdShell.Flag("force-nested", "Start the shell even from inside another saml2alibabacloud shell.").BoolVar(&shellFlags.ForceNested)
+
+	// `credential-process` command and settings
+	cmdCredentialProcess := app.Command("credential-process", "Print the STS credentials as JSON for an External mode AlibabaCloud CLI profile, logging in when they are expired.")
+	credentialProcessFlags := new(flags.LoginExecFlags)
+	credentialProcessFlags.CommonFlags = commonFlags
+	cmdCredentialProcess.Flag("profile", "The AlibabaCloud CLI profile to save the temporary credentials. (env: SAML2ALIBABACLOUD_PROFILE)").Envar("SAML2ALIBABACLOUD_PROFILE").Short('p').StringVar(&commonFlags.Profile)
 
 	// `script` command and settings
 	cmdScript := app.Command("script", "Emit a script that will export environment variables.")
-	scriptFlags := new(flags.LoginExecFlags)
-	scriptFlags.CommonFlags = commonFlags
+	scriptFlags := new(flags.ScriptFlags)
+	scriptFlags.LoginExecFlags = new(flags.LoginExecFlags)
+	scriptFlags.LoginExecFlags.CommonFlags = commonFlags
 	cmdScript.Flag("profile", "The AlibabaCloud CLI profile to save the temporary credentials. (env: SAML2ALIBABACLOUD_PROFILE)").Envar("SAML2ALIBABACLOUD_PROFILE").Short('p').StringVar(&commonFlags.Profile)
-	var shell string
 	cmdScript.
-		Flag("shell", "Type of shell environment. Options include: bash, powershell, fish").
+		Flag("shell", "Type of shell environment. Options include: "+strings.Join(commands.ScriptShells, ", ")).
 		Default("bash").
-		EnumVar(&shell, "bash", "powershell", "fish")
+		EnumVar(&scriptFlags.Shell, commands.ScriptShells...)
+	cmdScript.Flag("unset", "Emit the statements unsetting the environment variables instead.").BoolVar(&scriptFlags.Unset)
 
 	// Trigger the parsing of the command line inputs via kingpin
 	command := kingpin.MustParse(app.Parse(os.Args[1:]))
@@ -158,19 +270,47 @@ func main() {
 	var err error
 	switch command {
 	case cmdScript.FullCommand():
-		err = commands.Script(scriptFlags, shell)
+		err = commands.Script(scriptFlags)
 	case cmdLogin.FullCommand():
 		err = commands.Login(loginFlags)
+	case cmdLogout.FullCommand():
+		err = commands.Logout(logoutFlags)
+	case cmdStatus.FullCommand():
+		err = commands.Status(statusFlags)
+	case cmdDoctor.FullCommand():
+		err = commands.Doctor(doctorFlags)
 	case cmdExec.FullCommand():
 		err = commands.Exec(execFlags, *cmdLine)
 	case cmdConsole.FullCommand():
 		err = commands.Console(consoleFlags)
+	case cmdServe.FullCommand():
+		err = commands.Serve(serveFlags)
+	case cmdShell.FullCommand():
+		err = commands.Shell(shellFlags)
+	case cmdCredentialProcess.FullCommand():
+		err = commands.CredentialProcess(credentialProcessFlags)
 	case cmdListRoles.FullCommand():
 		err = commands.ListRoles(listRolesFlags)
+	case cmdConfigList.FullCommand():
+		err = commands.ConfigList(configCmdFlags)
+	case cmdConfigShow.FullCommand():
+		err = commands.ConfigShow(configCmdFlags)
+	case cmdConfigSet.FullCommand():
+		err = commands.ConfigSet(configCmdFlags)
+	case cmdConfigDelete.FullCommand():
+		err = commands.ConfigDelete(configCmdFlags)
+	case cmdConfigRename.FullCommand():
+		err = commands.ConfigRename(configCmdFlags)
+	case cmdConfigCopy.FullCommand():
+		err = commands.ConfigCopy(configCmdFlags)
 	case cmdConfigure.FullCommand():
 		err = commands.Configure(configFlags)
 	}
 
+	if exitErr, ok := errors.Cause(err).(*shell.ExitError); ok {
+		os.Exit(exitErr.Code)
+	}
+
 	if err != nil {
 		log.Printf(errtpl, err)
 		os.Exit(1)
@@ -10,6 +10,9 @@ var (
 
 	// ErrCredentialsNotFound returned when the credential can't be located in the native store.
 	ErrCredentialsNotFound = errors.New("credentials not found in native keychain")
+
+	// ErrListNotSupported returned when the native store can't list the stored credentials.
+	ErrListNotSupported = errors.New("native keychain can't list the stored credentials")
 )
 
 // Credentials holds the information shared between saml2alibabacloud and the credentials store.
@@ -37,6 +40,12 @@ type Helper interface {
 	SupportsCredentialStorage() bool
 }
 
+// Lister is implemented by the helpers which can list the stored credentials without reading the secrets.
+type Lister interface {
+	// List returns the stored server URLs and corresponding usernames.
+	List() (map[string]string, error)
+}
+
 // IsErrCredentialsNotFound returns true if the error
 // was caused by not having a set of credentials in a store.
 func IsErrCredentialsNotFound(err error) bool {
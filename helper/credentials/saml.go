@@ -28,6 +28,41 @@ func LookupCredentials(loginDetails *creds.LoginDetails, provider string) error
 	return nil
 }
 
+// DeleteCredentials delete the stored password and, for OneLogin, the client id and secret, missing ones are skipped.
+func DeleteCredentials(url, provider string) error {
+
+	err := CurrentHelper.Delete(url)
+	if err != nil && !IsErrCredentialsNotFound(err) {
+		return err
+	}
+
+	if provider == "OneLogin" {
+		err = CurrentHelper.Delete(path.Join(url, "/auth/oauth2/v2/token"))
+		if err != nil && !IsErrCredentialsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasCredentials check whether credentials are stored for the url without reading the secret, which would
+// trigger the access prompt of the macOS keychain.
+func HasCredentials(url string) (bool, error) {
+
+	lister, ok := CurrentHelper.(Lister)
+	if !ok {
+		return false, ErrListNotSupported
+	}
+
+	stored, err := lister.List()
+	if err != nil {
+		return false, err
+	}
+
+	_, ok = stored[url]
+	return ok, nil
+}
+
 // SaveCredentials save the user credentials.
 func SaveCredentials(url, username, password string) error {
 
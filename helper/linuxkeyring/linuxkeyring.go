@@ -49,11 +49,19 @@ func (kr *KeyringHelper) Add(creds *credentials.Credentials) error {
 }
 
 func (kr *KeyringHelper) Delete(serverURL string) error {
-	return kr.keyring.Remove(serverURL)
+	err := kr.keyring.Remove(serverURL)
+	if err == keyring.ErrKeyNotFound {
+		return credentials.ErrCredentialsNotFound
+	}
+	return err
 }
 
 func (kr *KeyringHelper) Get(serverURL string) (string, string, error) {
 	item, err := kr.keyring.Get(serverURL)
+	if err == keyring.ErrKeyNotFound {
+		logger.WithField("serverURL", serverURL).Debug("no credentials stored in keychain")
+		return "", "", credentials.ErrCredentialsNotFound
+	}
 	if err != nil {
 		logger.WithField("err", err).Error("keychain Get returned error")
 		return "", "", credentials.ErrCredentialsNotFound
@@ -67,6 +75,20 @@ func (kr *KeyringHelper) Get(serverURL string) (string, string, error) {
 	return creds.Username, creds.Secret, nil
 }
 
+// List returns the stored URLs, the usernames are only held in the secrets so they are left empty.
+func (kr *KeyringHelper) List() (map[string]string, error) {
+	keys, err := kr.keyring.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make(map[string]string)
+	for _, key := range keys {
+		resp[key] = ""
+	}
+	return resp, nil
+}
+
 func (KeyringHelper) SupportsCredentialStorage() bool {
 	return true
 }
@@ -87,7 +87,14 @@ func (h Osxkeychain) Delete(serverURL string) error {
 	errMsg := C.keychain_delete(s)
 	if errMsg != nil {
 		defer C.free(unsafe.Pointer(errMsg))
-		return errors.New(C.GoString(errMsg))
+		goMsg := C.GoString(errMsg)
+
+		if goMsg == errCredentialsNotFound {
+			logger.WithField("goMsg", goMsg).Debug("Delete credentials")
+			return credentials.ErrCredentialsNotFound
+		}
+
+		return errors.New(goMsg)
 	}
 
 	return nil
@@ -84,3 +84,18 @@ func TestMissingCredentials(t *testing.T) {
 		t.Fatalf("expected ErrCredentialsNotFound, got %v", err)
 	}
 }
+
+func TestDeleteMissingCredentials(t *testing.T) {
+	helper := Osxkeychain{}
+	err := helper.Delete("https://adsfasdf.wrewerwer.com/asdfsdddd")
+	if !credentials.IsErrCredentialsNotFound(err) {
+		t.Fatalf("expected ErrCredentialsNotFound, got %v", err)
+	}
+
+	defer func(current credentials.Helper) { credentials.CurrentHelper = current }(credentials.CurrentHelper)
+	credentials.CurrentHelper = helper
+
+	if err := credentials.DeleteCredentials("https://adsfasdf.wrewerwer.com/asdfsdddd", "OneLogin"); err != nil {
+		t.Fatalf("expected missing credentials to be skipped, got %v", err)
+	}
+}
@@ -87,22 +87,43 @@ func PromptForLoginDetails(loginDetails *creds.LoginDetails, provider string) er
 	return nil
 }
 
-// PromptForRamRoleSelection present a list of roles to the user for selection
-func PromptForRamRoleSelection(accounts []*AlibabaCloudAccount) (*RamRole, error) {
+// PromptForRamRoleSelection present a list of roles to the user for selection, the recently chosen role ARNs
+// are offered first with the most recent one as the default
+func PromptForRamRoleSelection(accounts []*AlibabaCloudAccount, recent []string) (*RamRole, error) {
 
 	roles := map[string]*RamRole{}
+	roleNames := map[string]string{}
 	var roleOptions []string
 
 	for _, account := range accounts {
 		for _, role := range account.Roles {
 			name := fmt.Sprintf("%s / %s", account.Name, role.Name)
 			roles[name] = role
+			roleNames[role.RoleARN] = name
 			roleOptions = append(roleOptions, name)
 		}
 	}
 
 	sort.Strings(roleOptions)
 
+	var recentOptions []string
+	for _, roleARN := range recent {
+		if name, ok := roleNames[roleARN]; ok {
+			recentOptions = append(recentOptions, name)
+			delete(roleNames, roleARN)
+		}
+	}
+
+	if len(recentOptions) > 0 {
+		options := recentOptions
+		for _, name := range roleOptions {
+			if !contains(recentOptions, name) {
+				options = append(options, name)
+			}
+		}
+		roleOptions = options
+	}
+
 	selectedRole, err := prompter.ChooseWithDefault("Please choose the role", roleOptions[0], roleOptions)
 	if err != nil {
 		return nil, errors.Wrap(err, "Role selection failed")
@@ -110,3 +131,12 @@ func PromptForRamRoleSelection(accounts []*AlibabaCloudAccount) (*RamRole, error
 
 	return roles[selectedRole], nil
 }
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
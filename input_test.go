@@ -3,7 +3,10 @@ package saml2alibabacloud
 import (
 	"testing"
 
+	"github.com/aliyun/saml2alibabacloud/mocks"
 	"github.com/aliyun/saml2alibabacloud/pkg/creds"
+	"github.com/aliyun/saml2alibabacloud/pkg/prompter"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestLoginDetails_Validate(t *testing.T) {
@@ -36,3 +39,32 @@ func TestLoginDetails_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestPromptForRamRoleSelectionRecent(t *testing.T) {
+	ramRoles, err := ParseRamRoles([]string{
+		"acs:ram::000000000001:role/admin,acs:ram::000000000001:saml-provider/example-idp",
+		"acs:ram::000000000001:role/readonly,acs:ram::000000000001:saml-provider/example-idp",
+		"acs:ram::000000000002:role/admin,acs:ram::000000000002:saml-provider/example-idp",
+	})
+	assert.Nil(t, err)
+
+	accounts := BuildAlibabaCloudAccounts(ramRoles, map[string]string{"000000000002": "prod"})
+
+	pr := &mocks.Prompter{}
+	prompter.SetPrompter(pr)
+	pr.Mock.On("ChooseWithDefault", "Please choose the role", "prod(000000000002) / admin", []string{
+		"prod(000000000002) / admin",
+		"000000000001 / readonly",
+		"000000000001 / admin",
+	}).Return("000000000001 / readonly", nil)
+
+	// roles which are no longer available are ignored
+	role, err := PromptForRamRoleSelection(accounts, []string{
+		"acs:ram::000000000002:role/admin",
+		"acs:ram::000000000003:role/admin",
+		"acs:ram::000000000001:role/readonly",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "acs:ram::000000000001:role/readonly", role.RoleARN)
+	pr.AssertExpectations(t)
+}
@@ -1,11 +1,15 @@
 package alibabacloudconfig
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
+	"time"
 
 	config "github.com/aliyun/aliyun-cli/config"
 	homedir "github.com/mitchellh/go-homedir"
@@ -23,14 +27,42 @@ var (
 	logger = logrus.WithField("pkg", "alibabacloudconfig")
 )
 
+const (
+	// DefaultRegion the region saved in a new profile when the idp account doesn't configure one
+	DefaultRegion = "cn-hangzhou"
+
+	// DefaultOutputFormat the output format saved in a new profile when the idp account doesn't configure one
+	DefaultOutputFormat = "json"
+
+	// DefaultLanguage the language saved in a new profile when the idp account doesn't configure one
+	DefaultLanguage = "en"
+
+	// metadataFilename the file next to the AlibabaCloud CLI configuration holding the credentials metadata
+	metadataFilename = "saml2alibabacloud.json"
+
+	// expiryWindow credentials which expire within this window are treated as expired
+	expiryWindow = time.Minute
+)
+
 // AliCloudCredentials represents the set of attributes used to authenticate to AlibabaCloud with a short lived session
 type AliCloudCredentials struct {
-	AliCloudAccessKey     string `json:"access_key_id"`
-	AliCloudSecretKey     string `json:"access_key_secret"`
-	AliCloudSessionToken  string `json:"ram_session_name"`
-	AliCloudSecurityToken string `json:"sts_token"`
-	PrincipalARN          string `json:"ram_role_arn"`
-	Region                string `json:"region,omitempty"`
+	AliCloudAccessKey     string    `json:"access_key_id"`
+	AliCloudSecretKey     string    `json:"access_key_secret"`
+	AliCloudSessionToken  string    `json:"ram_session_name"`
+	AliCloudSecurityToken string    `json:"sts_token"`
+	PrincipalARN          string    `json:"ram_role_arn"`
+	Region                string    `json:"region,omitempty"`
+	OutputFormat          string    `json:"output_format,omitempty"`
+	Language              string    `json:"language,omitempty"`
+	Expires               time.Time `json:"expiration,omitempty"`
+	SessionPolicyHash     string    `json:"session_policy_hash,omitempty"`
+}
+
+// profileMetadata holds the details of saved credentials which the AlibabaCloud CLI profile has no room for
+type profileMetadata struct {
+	PrincipalARN      string    `json:"ram_role_arn,omitempty"`
+	Expires           time.Time `json:"expiration"`
+	SessionPolicyHash string    `json:"session_policy_hash,omitempty"`
 }
 
 // CredentialsProvider loads AlibabaCloud CLI credentials file
@@ -70,17 +102,74 @@ func (p *CredentialsProvider) Save(alibabacloudCreds *AliCloudCredentials) error
 	if err != nil {
 		return err
 	}
+
+	// keep the settings the user may have changed on an existing profile
+	existing, _ := configuration.GetProfile(p.Profile)
 	profile := config.Profile{
 		Name:            p.Profile,
 		Mode:            config.StsToken,
 		AccessKeyId:     alibabacloudCreds.AliCloudAccessKey,
 		AccessKeySecret: alibabacloudCreds.AliCloudSecretKey,
 		StsToken:        alibabacloudCreds.AliCloudSecurityToken,
-		OutputFormat:    "json",
-		Language:        "en",
+		RegionId:        existing.RegionId,
+		OutputFormat:    existing.OutputFormat,
+		Language:        existing.Language,
+		Site:            existing.Site,
+		RetryTimeout:    existing.RetryTimeout,
+		RetryCount:      existing.RetryCount,
 	}
+	applyProfileSettings(&profile, alibabacloudCreds)
+
+	// the profile is replaced so only the process_command of the other profiles is kept
+	commands, err := loadProcessCommands()
+	if err != nil {
+		return err
+	}
+	delete(commands, p.Profile)
+
 	configuration.PutProfile(profile)
-	return config.SaveConfiguration(configuration)
+	err = config.SaveConfiguration(configuration)
+	if err != nil {
+		return err
+	}
+
+	err = saveProcessCommands(commands)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := loadMetadata()
+	if err != nil {
+		return err
+	}
+	metadata[p.Profile] = &profileMetadata{
+		PrincipalARN:      alibabacloudCreds.PrincipalARN,
+		Expires:           alibabacloudCreds.Expires,
+		SessionPolicyHash: alibabacloudCreds.SessionPolicyHash,
+	}
+	return saveMetadata(metadata)
+}
+
+// applyProfileSettings override the profile settings with the ones of the credentials, falling back to the defaults
+func applyProfileSettings(profile *config.Profile, alibabacloudCreds *AliCloudCredentials) {
+	if alibabacloudCreds.Region != "" {
+		profile.RegionId = alibabacloudCreds.Region
+	}
+	if alibabacloudCreds.OutputFormat != "" {
+		profile.OutputFormat = alibabacloudCreds.OutputFormat
+	}
+	if alibabacloudCreds.Language != "" {
+		profile.Language = alibabacloudCreds.Language
+	}
+	if profile.RegionId == "" {
+		profile.RegionId = DefaultRegion
+	}
+	if profile.OutputFormat == "" {
+		profile.OutputFormat = DefaultOutputFormat
+	}
+	if profile.Language == "" {
+		profile.Language = DefaultLanguage
+	}
 }
 
 // Load load the AlibabaCloud CLI credentials file
@@ -99,23 +188,146 @@ func (p *CredentialsProvider) Load() (*AliCloudCredentials, error) {
 		return nil, errors.New("profile not found in AlibabaCloud CLI credentials")
 	}
 
-	return &AliCloudCredentials{
+	alibabacloudCreds := &AliCloudCredentials{
 		AliCloudAccessKey:     profile.AccessKeyId,
 		AliCloudSecretKey:     profile.AccessKeySecret,
 		AliCloudSessionToken:  profile.RoleSessionName,
 		AliCloudSecurityToken: profile.StsToken,
 		PrincipalARN:          profile.RamRoleArn,
-	}, nil
+		Region:                profile.RegionId,
+		OutputFormat:          profile.OutputFormat,
+		Language:              profile.Language,
+	}
+
+	metadata, err := loadMetadata()
+	if err != nil {
+		return nil, err
+	}
+	if profileMetadata, ok := metadata[p.Profile]; ok {
+		alibabacloudCreds.Expires = profileMetadata.Expires
+		alibabacloudCreds.SessionPolicyHash = profileMetadata.SessionPolicyHash
+		if alibabacloudCreds.PrincipalARN == "" {
+			alibabacloudCreds.PrincipalARN = profileMetadata.PrincipalARN
+		}
+	}
+
+	return alibabacloudCreds, nil
 }
 
-// Expired checks if the current credentials are expired
+// Expired checks if the current credentials are expired, credentials saved without an expiry are treated as expired
 func (p *CredentialsProvider) Expired() bool {
-	// _, err := p.Load()
-	// if err != nil {
-	// 	return true
-	// }
+	alibabacloudCreds, err := p.Load()
+	if err != nil {
+		return true
+	}
+
+	if alibabacloudCreds.Expires.IsZero() {
+		return true
+	}
+
+	return time.Now().Add(expiryWindow).After(alibabacloudCreds.Expires)
+}
+
+// Delete remove the profile from the AlibabaCloud CLI configuration along with its metadata, the current profile
+// falls back to the default one when it is the deleted profile
+func (p *CredentialsProvider) Delete() error {
+	configuration, err := config.LoadConfiguration(config.GetConfigPath()+"/config.json", os.Stdout)
+	if err != nil {
+		return err
+	}
 
-	return true
+	commands, err := loadProcessCommands()
+	if err != nil {
+		return err
+	}
+	delete(commands, p.Profile)
+
+	profiles := []config.Profile{}
+	for _, profile := range configuration.Profiles {
+		if profile.Name != p.Profile {
+			profiles = append(profiles, profile)
+		}
+	}
+	configuration.Profiles = profiles
+	if configuration.CurrentProfile == p.Profile {
+		configuration.CurrentProfile = config.DefaultConfigProfileName
+	}
+
+	err = config.SaveConfiguration(configuration)
+	if err != nil {
+		return err
+	}
+
+	err = saveProcessCommands(commands)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := loadMetadata()
+	if err != nil {
+		return err
+	}
+	delete(metadata, p.Profile)
+	return saveMetadata(metadata)
+}
+
+// ManagedProfiles the names of the profiles saved by saml2alibabacloud, the ones holding credentials and the
+// External mode ones running credential-process
+func ManagedProfiles() ([]string, error) {
+	metadata, err := loadMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	commands, err := loadProcessCommands()
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := []string{}
+	for name := range metadata {
+		profiles = append(profiles, name)
+	}
+	for name, command := range commands {
+		if _, ok := metadata[name]; !ok && strings.Contains(command, " credential-process ") {
+			profiles = append(profiles, name)
+		}
+	}
+	sort.Strings(profiles)
+
+	return profiles, nil
+}
+
+func metadataPath() string {
+	return filepath.Join(config.GetConfigPath(), metadataFilename)
+}
+
+// loadMetadata load the metadata saved alongside the AlibabaCloud CLI configuration
+func loadMetadata() (map[string]*profileMetadata, error) {
+	metadata := map[string]*profileMetadata{}
+
+	data, err := ioutil.ReadFile(metadataPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return metadata, nil
+		}
+		return nil, errors.Wrap(err, "unable to read credentials metadata")
+	}
+
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, errors.Wrap(err, "unable to parse credentials metadata")
+	}
+
+	return metadata, nil
+}
+
+func saveMetadata(metadata map[string]*profileMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(metadataPath(), data, 0600)
 }
 
 // ensureConfigExists verify that the config file exists
@@ -166,6 +378,11 @@ func (p *CredentialsProvider) resolveFilename() (string, error) {
 	return p.Filename, nil
 }
 
+// ConfigFile the location of the AlibabaCloud CLI configuration file
+func ConfigFile() (string, error) {
+	return locateConfigFile()
+}
+
 func locateConfigFile() (string, error) {
 
 	var name string
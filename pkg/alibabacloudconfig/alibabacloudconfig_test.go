@@ -3,6 +3,7 @@ package alibabacloudconfig
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -38,3 +39,120 @@ func TestUpdateSamlConfig(t *testing.T) {
 
 	os.Remove(".credentials")
 }
+
+func TestExpired(t *testing.T) {
+	_, cleanup := tempHome(t)
+	defer cleanup()
+
+	sharedCreds := NewSharedCredentials("saml")
+
+	// no credentials saved yet
+	assert.True(t, sharedCreds.Expired())
+
+	expires := time.Now().Add(time.Hour).Round(time.Second)
+	err := sharedCreds.Save(&AliCloudCredentials{
+		AliCloudAccessKey:     "testid",
+		AliCloudSecretKey:     "testsecret",
+		AliCloudSecurityToken: "testtoken",
+		PrincipalARN:          "acs:ram::123123123123:assumed-role/admin/user",
+		Expires:               expires,
+		SessionPolicyHash:     "9f86d081",
+	})
+	assert.Nil(t, err)
+	assert.False(t, sharedCreds.Expired())
+
+	alibabacloudCreds, err := sharedCreds.Load()
+	assert.Nil(t, err)
+	assert.True(t, expires.Equal(alibabacloudCreds.Expires))
+	assert.Equal(t, "acs:ram::123123123123:assumed-role/admin/user", alibabacloudCreds.PrincipalARN)
+	assert.Equal(t, "9f86d081", alibabacloudCreds.SessionPolicyHash)
+
+	err = sharedCreds.Save(&AliCloudCredentials{
+		AliCloudAccessKey:     "testid",
+		AliCloudSecretKey:     "testsecret",
+		AliCloudSecurityToken: "testtoken",
+		Expires:               time.Now().Add(-time.Minute),
+	})
+	assert.Nil(t, err)
+	assert.True(t, sharedCreds.Expired())
+}
+
+func TestSaveProfileSettings(t *testing.T) {
+	_, cleanup := tempHome(t)
+	defer cleanup()
+
+	sharedCreds := NewSharedCredentials("saml")
+
+	// a new profile falls back to the defaults
+	err := sharedCreds.Save(&AliCloudCredentials{
+		AliCloudAccessKey:     "testid",
+		AliCloudSecretKey:     "testsecret",
+		AliCloudSecurityToken: "testtoken",
+	})
+	assert.Nil(t, err)
+
+	alibabacloudCreds, err := sharedCreds.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultRegion, alibabacloudCreds.Region)
+	assert.Equal(t, DefaultOutputFormat, alibabacloudCreds.OutputFormat)
+	assert.Equal(t, DefaultLanguage, alibabacloudCreds.Language)
+
+	// settings from the idp account replace the saved ones
+	err = sharedCreds.Save(&AliCloudCredentials{
+		AliCloudAccessKey:     "testid",
+		AliCloudSecretKey:     "testsecret",
+		AliCloudSecurityToken: "testtoken",
+		Region:                "ap-southeast-1",
+		OutputFormat:          "table",
+		Language:              "zh",
+	})
+	assert.Nil(t, err)
+
+	// settings left empty on the idp account keep the saved ones
+	err = sharedCreds.Save(&AliCloudCredentials{
+		AliCloudAccessKey:     "testid2",
+		AliCloudSecretKey:     "testsecret2",
+		AliCloudSecurityToken: "testtoken2",
+	})
+	assert.Nil(t, err)
+
+	alibabacloudCreds, err = sharedCreds.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "testid2", alibabacloudCreds.AliCloudAccessKey)
+	assert.Equal(t, "ap-southeast-1", alibabacloudCreds.Region)
+	assert.Equal(t, "table", alibabacloudCreds.OutputFormat)
+	assert.Equal(t, "zh", alibabacloudCreds.Language)
+}
+
+func TestDelete(t *testing.T) {
+	_, cleanup := tempHome(t)
+	defer cleanup()
+
+	for _, profile := range []string{"saml", "other"} {
+		err := NewSharedCredentials(profile).Save(&AliCloudCredentials{
+			AliCloudAccessKey: "testid",
+			Expires:           time.Now().Add(time.Hour),
+		})
+		assert.Nil(t, err)
+	}
+	err := NewSharedCredentials("external").SaveExternal("saml2alibabacloud credential-process --profile=saml", &AliCloudCredentials{})
+	assert.Nil(t, err)
+
+	profiles, err := ManagedProfiles()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"external", "other", "saml"}, profiles)
+
+	err = NewSharedCredentials("saml").Delete()
+	assert.Nil(t, err)
+
+	_, err = NewSharedCredentials("saml").Load()
+	assert.NotNil(t, err)
+
+	profiles, err = ManagedProfiles()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"external", "other"}, profiles)
+
+	// deleting a missing profile is a no-op
+	err = NewSharedCredentials("missing").Delete()
+	assert.Nil(t, err)
+}
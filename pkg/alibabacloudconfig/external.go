@@ -0,0 +1,158 @@
+package alibabacloudconfig
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	config "github.com/aliyun/aliyun-cli/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	// External the mode of an AlibabaCloud CLI profile which runs process_command to get its credentials
+	External = config.AuthenticateMode("External")
+
+	// processCommandKey the key of the command run for an External mode profile, the vendored
+	// AlibabaCloud CLI configuration predates it so it is kept by patching the raw file
+	processCommandKey = "process_command"
+)
+
+// ProcessCredentials the credentials printed for the process_command of an External mode profile
+type ProcessCredentials struct {
+	Mode            string `json:"mode"`
+	AccessKeyID     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	StsToken        string `json:"sts_token"`
+}
+
+// NewProcessCredentials convert the credentials into the output expected from a process_command
+func NewProcessCredentials(alibabacloudCreds *AliCloudCredentials) *ProcessCredentials {
+	return &ProcessCredentials{
+		Mode:            string(config.StsToken),
+		AccessKeyID:     alibabacloudCreds.AliCloudAccessKey,
+		AccessKeySecret: alibabacloudCreds.AliCloudSecretKey,
+		StsToken:        alibabacloudCreds.AliCloudSecurityToken,
+	}
+}
+
+// SaveExternal persist an External mode profile which runs the process command whenever the AlibabaCloud CLI needs credentials,
+// only the region, output format and language of the credentials are used
+func (p *CredentialsProvider) SaveExternal(processCommand string, alibabacloudCreds *AliCloudCredentials) error {
+	configuration, err := config.LoadConfiguration(configFilename(), os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	existing, _ := configuration.GetProfile(p.Profile)
+	profile := config.Profile{
+		Name:         p.Profile,
+		Mode:         External,
+		RegionId:     existing.RegionId,
+		OutputFormat: existing.OutputFormat,
+		Language:     existing.Language,
+		Site:         existing.Site,
+		RetryTimeout: existing.RetryTimeout,
+		RetryCount:   existing.RetryCount,
+	}
+	applyProfileSettings(&profile, alibabacloudCreds)
+
+	commands, err := loadProcessCommands()
+	if err != nil {
+		return err
+	}
+
+	configuration.PutProfile(profile)
+	err = config.SaveConfiguration(configuration)
+	if err != nil {
+		return err
+	}
+
+	commands[p.Profile] = processCommand
+	return saveProcessCommands(commands)
+}
+
+func configFilename() string {
+	return filepath.Join(config.GetConfigPath(), "config.json")
+}
+
+// loadProcessCommands load the process_command of every profile which has one
+func loadProcessCommands() (map[string]string, error) {
+	commands := map[string]string{}
+
+	raw, err := loadRawConfiguration()
+	if err != nil || raw == nil {
+		return commands, err
+	}
+
+	for _, profile := range rawProfiles(raw) {
+		name, _ := profile["name"].(string)
+		command, ok := profile[processCommandKey].(string)
+		if ok && command != "" {
+			commands[name] = command
+		}
+	}
+
+	return commands, nil
+}
+
+// saveProcessCommands put back the process_command of the profiles, saving the configuration through the
+// AlibabaCloud CLI package drops them
+func saveProcessCommands(commands map[string]string) error {
+	if len(commands) == 0 {
+		return nil
+	}
+
+	raw, err := loadRawConfiguration()
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		return errors.New("AlibabaCloud CLI configuration not found")
+	}
+
+	for _, profile := range rawProfiles(raw) {
+		name, _ := profile["name"].(string)
+		if command, ok := commands[name]; ok {
+			profile[processCommandKey] = command
+		}
+	}
+
+	data, err := json.MarshalIndent(raw, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(configFilename(), data, 0600)
+}
+
+func loadRawConfiguration() (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(configFilename())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "unable to read AlibabaCloud CLI configuration")
+	}
+
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrap(err, "unable to parse AlibabaCloud CLI configuration")
+	}
+
+	return raw, nil
+}
+
+func rawProfiles(raw map[string]interface{}) []map[string]interface{} {
+	profiles := []map[string]interface{}{}
+
+	list, _ := raw["profiles"].([]interface{})
+	for _, item := range list {
+		if profile, ok := item.(map[string]interface{}); ok {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	return profiles
+}
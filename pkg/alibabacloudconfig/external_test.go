@@ -0,0 +1,60 @@
+package alibabacloudconfig
+
+import (
+	"os"
+	"testing"
+
+	config "github.com/aliyun/aliyun-cli/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveExternal(t *testing.T) {
+	_, cleanup := tempHome(t)
+	defer cleanup()
+
+	err := NewSharedCredentials("external").SaveExternal("saml2alibabacloud credential-process --profile=saml", &AliCloudCredentials{Region: "ap-southeast-1"})
+	require.Nil(t, err)
+
+	// saving another profile keeps the process command
+	err = NewSharedCredentials("saml").Save(&AliCloudCredentials{
+		AliCloudAccessKey:     "testid",
+		AliCloudSecretKey:     "testsecret",
+		AliCloudSecurityToken: "testtoken",
+	})
+	require.Nil(t, err)
+
+	configuration, err := config.LoadConfiguration(configFilename(), os.Stdout)
+	require.Nil(t, err)
+	profile, ok := configuration.GetProfile("external")
+	require.True(t, ok)
+	assert.Equal(t, External, profile.Mode)
+	assert.Equal(t, "ap-southeast-1", profile.RegionId)
+
+	commands, err := loadProcessCommands()
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"external": "saml2alibabacloud credential-process --profile=saml"}, commands)
+
+	// saving credentials over the external profile drops the process command
+	err = NewSharedCredentials("external").Save(&AliCloudCredentials{AliCloudAccessKey: "testid"})
+	require.Nil(t, err)
+
+	commands, err = loadProcessCommands()
+	require.Nil(t, err)
+	assert.Empty(t, commands)
+}
+
+func TestNewProcessCredentials(t *testing.T) {
+	processCreds := NewProcessCredentials(&AliCloudCredentials{
+		AliCloudAccessKey:     "testid",
+		AliCloudSecretKey:     "testsecret",
+		AliCloudSecurityToken: "testtoken",
+	})
+
+	assert.Equal(t, &ProcessCredentials{
+		Mode:            "StsToken",
+		AccessKeyID:     "testid",
+		AccessKeySecret: "testsecret",
+		StsToken:        "testtoken",
+	}, processCreds)
+}
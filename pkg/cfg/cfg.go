@@ -2,7 +2,12 @@ package cfg
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
@@ -24,29 +29,60 @@ const (
 	// see https://www.alibabacloud.com/help/doc-detail/166256.htm
 	DefaultSessionDuration = 3600
 
+	// DefaultIDPAccount the idp account used when none is selected
+	DefaultIDPAccount = "default"
+
 	// DefaultProfile this is the default profile name used to save the credentials in the `aliyun` cli
 	// see https://www.alibabacloud.com/help/doc-detail/121259.htm
 	DefaultProfile = "saml"
+
+	// AccountAliasesSection the section of the configuration file mapping account ids to friendly names
+	AccountAliasesSection = "account_aliases"
+
+	// RoleAliasesSection the section of the configuration file mapping friendly names to role ARNs
+	RoleAliasesSection = "role_aliases"
 )
 
 // IDPAccount saml IDP account
 type IDPAccount struct {
-	AppID             string `ini:"app_id"` // used by OneLogin and AzureAD
-	URL               string `ini:"url"`
-	Username          string `ini:"username"`
-	Provider          string `ini:"provider"`
-	MFA               string `ini:"mfa"`
-	SkipVerify        bool   `ini:"skip_verify"`
-	Timeout           int    `ini:"timeout"`
-	AlibabaCloudURN   string `ini:"alibabacloud_urn"`
-	SessionDuration   int    `ini:"alibabacloud_session_duration"`
-	Profile           string `ini:"alibabacloud_profile"`
-	ResourceID        string `ini:"resource_id"` // used by F5APM
-	Subdomain         string `ini:"subdomain"`   // used by OneLogin
-	RoleARN           string `ini:"role_arn"`
-	Region            string `ini:"region"`
-	HTTPAttemptsCount string `ini:"http_attempts_count"`
-	HTTPRetryDelay    string `ini:"http_retry_delay"`
+	AppID             string   `ini:"app_id"` // used by OneLogin and AzureAD
+	URL               string   `ini:"url"`
+	Username          string   `ini:"username"`
+	Provider          string   `ini:"provider"`
+	MFA               string   `ini:"mfa"`
+	SkipVerify        bool     `ini:"skip_verify"`
+	Timeout           int      `ini:"timeout"`
+	AlibabaCloudURN   string   `ini:"alibabacloud_urn"`
+	SessionDuration   int      `ini:"alibabacloud_session_duration"`
+	Profile           string   `ini:"alibabacloud_profile"`
+	ResourceID        string   `ini:"resource_id"` // used by F5APM
+	Subdomain         string   `ini:"subdomain"`   // used by OneLogin
+	RoleARN           string   `ini:"role_arn"`
+	Region            string   `ini:"region"`
+	OutputFormat      string   `ini:"output_format"` // saved in the AlibabaCloud CLI profile
+	Language          string   `ini:"language"`      // saved in the AlibabaCloud CLI profile
+	STSEndpoint       string   `ini:"sts_endpoint"`
+	STSRegion         string   `ini:"sts_region"`
+	HTTPAttemptsCount string   `ini:"http_attempts_count"`
+	HTTPRetryDelay    string   `ini:"http_retry_delay"`
+	IDPCertificate    string   `ini:"idp_certificate"`         // PEM file used to verify the SAML response signature
+	IDPMetadataURL    string   `ini:"idp_metadata_url"`        // metadata published by the IdP, used to verify the SAML response signature
+	SPPrivateKey      string   `ini:"sp_private_key"`          // PEM file used to decrypt encrypted assertions
+	OfflineRoles      bool     `ini:"offline_roles"`           // build the accounts from the role ARNs instead of the role selection page
+	EnrichAliases     bool     `ini:"enrich_account_aliases"`  // fill the missing aliases from the role selection page
+	RecentRoles       int      `ini:"recent_roles"`            // number of chosen roles offered first, 0 uses the default and -1 disables it
+	RoleProfiles      []string `ini:"role_profiles" delim:","` // role=profile pairs logged in to at once by login
+	RoleChain         []string `ini:"role_chain" delim:","`    // roles assumed one after the other once logged in, arn[;session=name][;duration=seconds]
+	SessionPolicy     string   `ini:"session_policy"`          // inline JSON or a file holding the policy attached to the STS requests
+
+	// Name the name the idp account was selected by, e.g. with --idp-account
+	Name string `ini:"-"`
+
+	// AccountAliases maps account ids to names, loaded from the account_aliases section
+	AccountAliases map[string]string `ini:"-"`
+
+	// RoleAliases maps names usable as role_arn or --role to role ARNs, loaded from the role_aliases section
+	RoleAliases map[string]string `ini:"-"`
 }
 
 func (ia IDPAccount) String() string {
@@ -118,6 +154,11 @@ func (ia *IDPAccount) Validate() error {
 		return errors.New("Profile empty in idp account")
 	}
 
+	// every profile would end up with the credentials of the last hop of the chain
+	if len(ia.RoleProfiles) > 0 && len(ia.RoleChain) > 0 {
+		return errors.New("role_chain can't be combined with role_profiles in idp account")
+	}
+
 	return nil
 }
 
@@ -172,11 +213,7 @@ func (cm *ConfigManager) SaveIDPAccount(idpAccountName string, account *IDPAccou
 		return errors.Wrap(err, "Unable to save account to configuration file")
 	}
 
-	err = cfg.SaveTo(cm.configPath)
-	if err != nil {
-		return errors.Wrap(err, "Failed to save configuration file")
-	}
-	return nil
+	return cm.save(cfg)
 }
 
 // LoadIDPAccount load the idp account and default to an empty one if it doesn't exist
@@ -197,6 +234,263 @@ func (cm *ConfigManager) LoadIDPAccount(idpAccountName string) (*IDPAccount, err
 	return account, nil
 }
 
+// LookupIDPAccount load the idp account, unlike LoadIDPAccount a missing account is an error which suggests
+// the closest configured names
+func (cm *ConfigManager) LookupIDPAccount(idpAccountName string) (*IDPAccount, error) {
+
+	cfg, err := ini.LoadSources(ini.LoadOptions{Loose: true}, cm.configPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load configuration file")
+	}
+
+	if _, err := cfg.GetSection(idpAccountName); err != nil || isReservedSection(idpAccountName) {
+		return nil, newAccountNotFoundError(cfg, idpAccountName)
+	}
+
+	account, err := readAccount(idpAccountName, cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read idp account")
+	}
+
+	return account, nil
+}
+
+// IDPAccountExists check whether the configuration file has a section for the idp account
+func (cm *ConfigManager) IDPAccountExists(idpAccountName string) (bool, error) {
+
+	names, err := cm.IDPAccountNames()
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range names {
+		if name == idpAccountName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// IDPAccountNames the names of the idp accounts in the configuration file, in the order they are configured
+func (cm *ConfigManager) IDPAccountNames() ([]string, error) {
+
+	cfg, err := ini.LoadSources(ini.LoadOptions{Loose: true}, cm.configPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load configuration file")
+	}
+
+	names := []string{}
+	for _, name := range cfg.SectionStrings() {
+		if !isReservedSection(name) {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// IDPAccountKeys the keys an idp account section can hold
+func IDPAccountKeys() []string {
+	keys := []string{}
+
+	t := reflect.TypeOf(IDPAccount{})
+	for i := 0; i < t.NumField(); i++ {
+		if key := strings.Split(t.Field(i).Tag.Get("ini"), ",")[0]; key != "" && key != "-" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// IDPAccountSection the keys and values set in the section of the idp account, in the order they appear in the file
+func (cm *ConfigManager) IDPAccountSection(idpAccountName string) ([][2]string, error) {
+
+	_, sec, err := cm.loadSection(idpAccountName)
+	if err != nil {
+		return nil, err
+	}
+
+	values := [][2]string{}
+	for _, key := range sec.Keys() {
+		values = append(values, [2]string{key.Name(), key.Value()})
+	}
+
+	return values, nil
+}
+
+// SetIDPAccountValue set a key of the idp account, the change is only saved when the account remains valid
+func (cm *ConfigManager) SetIDPAccountValue(idpAccountName, key, value string) error {
+
+	if !isIDPAccountKey(key) {
+		return errors.Errorf("unknown key %s, expected one of: %s", key, strings.Join(IDPAccountKeys(), ", "))
+	}
+
+	cfg, sec, err := cm.loadSection(idpAccountName)
+	if err != nil {
+		return err
+	}
+
+	sec.Key(key).SetValue(value)
+
+	if err := validateSection(sec); err != nil {
+		return err
+	}
+
+	return cm.save(cfg)
+}
+
+// DeleteIDPAccount remove the section of the idp account
+func (cm *ConfigManager) DeleteIDPAccount(idpAccountName string) error {
+
+	cfg, _, err := cm.loadSection(idpAccountName)
+	if err != nil {
+		return err
+	}
+
+	cfg.DeleteSection(idpAccountName)
+
+	return cm.save(cfg)
+}
+
+// CopyIDPAccount copy the section of the idp account, comments included, to a new section
+func (cm *ConfigManager) CopyIDPAccount(idpAccountName, newName string) error {
+
+	cfg, err := cm.copySection(idpAccountName, newName)
+	if err != nil {
+		return err
+	}
+
+	return cm.save(cfg)
+}
+
+// RenameIDPAccount move the section of the idp account, comments included, to a new name
+func (cm *ConfigManager) RenameIDPAccount(idpAccountName, newName string) error {
+
+	cfg, err := cm.copySection(idpAccountName, newName)
+	if err != nil {
+		return err
+	}
+
+	cfg.DeleteSection(idpAccountName)
+
+	return cm.save(cfg)
+}
+
+func (cm *ConfigManager) copySection(idpAccountName, newName string) (*ini.File, error) {
+
+	cfg, sec, err := cm.loadSection(idpAccountName)
+	if err != nil {
+		return nil, err
+	}
+
+	if isReservedSection(newName) {
+		return nil, errors.Errorf("%s is reserved and can't be used as an idp account name", newName)
+	}
+	if _, err := cfg.GetSection(newName); err == nil {
+		return nil, errors.Errorf("idp account %s already exists", newName)
+	}
+
+	newSec, err := cfg.NewSection(newName)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to build a new section in configuration file")
+	}
+	newSec.Comment = sec.Comment
+
+	for _, key := range sec.Keys() {
+		newKey, err := newSec.NewKey(key.Name(), key.Value())
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to copy key %s", key.Name())
+		}
+		newKey.Comment = key.Comment
+	}
+
+	if err := validateSection(newSec); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadSection load the configuration file and the existing section of the idp account
+func (cm *ConfigManager) loadSection(idpAccountName string) (*ini.File, *ini.Section, error) {
+
+	cfg, err := ini.LoadSources(ini.LoadOptions{Loose: true}, cm.configPath)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Unable to load configuration file")
+	}
+
+	if isReservedSection(idpAccountName) {
+		return nil, nil, errors.Errorf("%s is not an idp account", idpAccountName)
+	}
+
+	sec, err := cfg.GetSection(idpAccountName)
+	if err != nil {
+		return nil, nil, newAccountNotFoundError(cfg, idpAccountName)
+	}
+
+	return cfg, sec, nil
+}
+
+// save write the configuration file atomically, a temporary file in the same directory replaces it once written
+func (cm *ConfigManager) save(cfg *ini.File) error {
+
+	// a symlinked file, e.g. from a dotfile manager, is written to its target so the link is kept
+	configPath := cm.configPath
+	if target, err := filepath.EvalSymlinks(configPath); err == nil {
+		configPath = target
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(configPath), filepath.Base(configPath)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "Failed to save configuration file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := cfg.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "Failed to save configuration file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "Failed to save configuration file")
+	}
+
+	mode := os.FileMode(0600)
+	if fi, err := os.Stat(configPath); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return errors.Wrap(err, "Failed to save configuration file")
+	}
+
+	if err := os.Rename(tmp.Name(), configPath); err != nil {
+		return errors.Wrap(err, "Failed to save configuration file")
+	}
+	return nil
+}
+
+func validateSection(sec *ini.Section) error {
+	account := NewIDPAccount()
+	// unlike MapTo a value which doesn't parse, e.g. timeout = abc, is an error
+	if err := sec.StrictMapTo(account); err != nil {
+		return errors.Wrap(err, "Unable to map account")
+	}
+	return errors.Wrap(account.Validate(), "Account validation failed")
+}
+
+func isIDPAccountKey(key string) bool {
+	for _, k := range IDPAccountKeys() {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isReservedSection(name string) bool {
+	return name == ini.DefaultSection || name == AccountAliasesSection || name == RoleAliasesSection
+}
+
 func readAccount(idpAccountName string, cfg *ini.File) (*IDPAccount, error) {
 
 	account := NewIDPAccount()
@@ -208,5 +502,18 @@ func readAccount(idpAccountName string, cfg *ini.File) (*IDPAccount, error) {
 		return nil, errors.Wrap(err, "Unable to map account")
 	}
 
+	// the delimiter of the tag would split the commas of a /regex/ role
+	if key, err := sec.GetKey("role_profiles"); err == nil {
+		account.RoleProfiles = splitRoleProfiles(key.String())
+	}
+
+	if aliasSec, err := cfg.GetSection(AccountAliasesSection); err == nil {
+		account.AccountAliases = aliasSec.KeysHash()
+	}
+
+	if aliasSec, err := cfg.GetSection(RoleAliasesSection); err == nil {
+		account.RoleAliases = aliasSec.KeysHash()
+	}
+
 	return account, nil
 }
@@ -1,9 +1,13 @@
 package cfg
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/pkg/errors"
+
 	"github.com/stretchr/testify/require"
 )
 
@@ -72,3 +76,185 @@ func TestNewConfigManagerSave(t *testing.T) {
 	os.Remove(throwAwayConfig)
 
 }
+
+func TestNewConfigManagerLoadAliases(t *testing.T) {
+
+	err := ioutil.WriteFile(throwAwayConfig, []byte(`[testing3]
+url = https://id.whatever.com
+offline_roles = true
+
+[account_aliases]
+123456789012 = prod
+210987654321 = dev
+
+[role_aliases]
+prod-admin = acs:ram::123456789012:role/admin
+`), 0600)
+	require.Nil(t, err)
+	defer os.Remove(throwAwayConfig)
+
+	cfgm, err := NewConfigManager(throwAwayConfig)
+	require.Nil(t, err)
+
+	idpAccount, err := cfgm.LoadIDPAccount("testing3")
+	require.Nil(t, err)
+	require.True(t, idpAccount.OfflineRoles)
+	require.Equal(t, map[string]string{
+		"123456789012": "prod",
+		"210987654321": "dev",
+	}, idpAccount.AccountAliases)
+	require.Equal(t, map[string]string{
+		"prod-admin": "acs:ram::123456789012:role/admin",
+	}, idpAccount.RoleAliases)
+}
+
+func TestIDPAccountNames(t *testing.T) {
+
+	err := ioutil.WriteFile(throwAwayConfig, []byte(`[work]
+url = https://id.whatever.com
+
+[account_aliases]
+123456789012 = prod
+
+[personal]
+url = https://id.example.com
+`), 0600)
+	require.Nil(t, err)
+	defer os.Remove(throwAwayConfig)
+
+	cfgm, err := NewConfigManager(throwAwayConfig)
+	require.Nil(t, err)
+
+	names, err := cfgm.IDPAccountNames()
+	require.Nil(t, err)
+	require.Equal(t, []string{"work", "personal"}, names)
+}
+
+func TestManageIDPAccounts(t *testing.T) {
+
+	err := ioutil.WriteFile(throwAwayConfig, []byte(`# the work account
+[work]
+url      = https://id.whatever.com
+provider = Okta
+mfa      = Auto
+`), 0600)
+	require.Nil(t, err)
+	defer os.Remove(throwAwayConfig)
+
+	cfgm, err := NewConfigManager(throwAwayConfig)
+	require.Nil(t, err)
+
+	require.Nil(t, cfgm.SetIDPAccountValue("work", "region", "ap-southeast-1"))
+	require.NotNil(t, cfgm.SetIDPAccountValue("work", "provider", ""), "an invalid change is rejected")
+	require.NotNil(t, cfgm.SetIDPAccountValue("work", "unknown", "value"))
+	require.Equal(t, ErrIdpAccountNotFound, errors.Cause(cfgm.SetIDPAccountValue("missing", "region", "cn-hangzhou")))
+
+	require.Nil(t, cfgm.CopyIDPAccount("work", "personal"))
+	require.NotNil(t, cfgm.CopyIDPAccount("work", "personal"), "an existing account isn't overwritten")
+	require.Nil(t, cfgm.RenameIDPAccount("personal", "home"))
+	require.NotNil(t, cfgm.RenameIDPAccount("home", AccountAliasesSection))
+
+	names, err := cfgm.IDPAccountNames()
+	require.Nil(t, err)
+	require.Equal(t, []string{"work", "home"}, names)
+
+	values, err := cfgm.IDPAccountSection("home")
+	require.Nil(t, err)
+	require.Equal(t, [][2]string{
+		{"url", "https://id.whatever.com"},
+		{"provider", "Okta"},
+		{"mfa", "Auto"},
+		{"region", "ap-southeast-1"},
+	}, values)
+
+	require.Nil(t, cfgm.DeleteIDPAccount("work"))
+
+	data, err := ioutil.ReadFile(throwAwayConfig)
+	require.Nil(t, err)
+	require.Equal(t, `# the work account
+[home]
+url      = https://id.whatever.com
+provider = Okta
+mfa      = Auto
+region   = ap-southeast-1
+
+`, string(data))
+}
+
+func TestSetIDPAccountValueInvalid(t *testing.T) {
+
+	err := ioutil.WriteFile(throwAwayConfig, []byte(`[work]
+url      = https://id.whatever.com
+provider = Okta
+mfa      = Auto
+`), 0600)
+	require.Nil(t, err)
+	defer os.Remove(throwAwayConfig)
+
+	cfgm, err := NewConfigManager(throwAwayConfig)
+	require.Nil(t, err)
+
+	require.NotNil(t, cfgm.SetIDPAccountValue("work", "timeout", "abc"))
+	require.NotNil(t, cfgm.SetIDPAccountValue("work", "skip_verify", "maybe"))
+	require.NotNil(t, cfgm.SetIDPAccountValue("work", "alibabacloud_session_duration", "1h"))
+
+	require.Nil(t, cfgm.SetIDPAccountValue("work", "timeout", "30"))
+	require.Nil(t, cfgm.SetIDPAccountValue("work", "skip_verify", "true"))
+
+	account, err := cfgm.LookupIDPAccount("work")
+	require.Nil(t, err)
+	require.Equal(t, 30, account.Timeout)
+	require.True(t, account.SkipVerify)
+}
+
+func TestSaveSymlinkedConfig(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "saml2alibabacloud")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "dotfiles.ini")
+	err = ioutil.WriteFile(target, []byte(`[work]
+url      = https://id.whatever.com
+provider = Okta
+mfa      = Auto
+`), 0640)
+	require.Nil(t, err)
+
+	link := filepath.Join(dir, ".saml2alibabacloud")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("symlinks aren't supported", err)
+	}
+
+	cfgm, err := NewConfigManager(link)
+	require.Nil(t, err)
+
+	require.Nil(t, cfgm.SetIDPAccountValue("work", "region", "ap-southeast-1"))
+
+	fi, err := os.Lstat(link)
+	require.Nil(t, err)
+	require.True(t, fi.Mode()&os.ModeSymlink != 0, "the link is kept")
+
+	fi, err = os.Stat(target)
+	require.Nil(t, err)
+	require.Equal(t, os.FileMode(0640), fi.Mode().Perm())
+
+	data, err := ioutil.ReadFile(target)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "ap-southeast-1")
+}
+
+func TestValidateRoleChainWithRoleProfiles(t *testing.T) {
+	idpAccount := NewIDPAccount()
+	idpAccount.URL = "https://id.whatever.com"
+	idpAccount.Provider = "keycloak"
+	idpAccount.MFA = "Auto"
+	idpAccount.RoleProfiles = []string{"dev-admin=dev", "prod-admin=prod"}
+	require.Nil(t, idpAccount.Validate())
+
+	idpAccount.RoleChain = []string{"acs:ram::000000000001:role/target"}
+	require.EqualError(t, idpAccount.Validate(), "role_chain can't be combined with role_profiles in idp account")
+
+	idpAccount.RoleProfiles = nil
+	require.Nil(t, idpAccount.Validate())
+}
@@ -0,0 +1,58 @@
+package cfg
+
+import (
+	"strings"
+)
+
+// SplitRoleProfile split a ROLE=PROFILE pair, a /regex/ role ends at the last /= so the = and , it may
+// hold, e.g. /env=prod/=prod, are kept in the role
+func SplitRoleProfile(value string) (string, string, bool) {
+	value = strings.TrimSpace(value)
+
+	if strings.HasPrefix(value, "/") {
+		i := strings.LastIndex(value, "/=")
+		if i <= 0 {
+			return "", "", false
+		}
+		return value[:i+1], strings.TrimSpace(value[i+2:]), true
+	}
+
+	i := strings.LastIndex(value, "=")
+	if i < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(value[:i]), strings.TrimSpace(value[i+1:]), true
+}
+
+// splitRoleProfiles split the comma separated role_profiles, the commas of a /regex/ role,
+// e.g. /a{1,3}/=dev, don't end the pair
+func splitRoleProfiles(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	pairs := []string{}
+	pair := ""
+	for _, token := range strings.Split(value, ",") {
+		if pair != "" {
+			pair += "," + token
+		} else {
+			pair = token
+		}
+
+		trimmed := strings.TrimSpace(pair)
+		if strings.HasPrefix(trimmed, "/") && !strings.Contains(trimmed[1:], "/=") {
+			continue
+		}
+
+		pairs = append(pairs, trimmed)
+		pair = ""
+	}
+
+	// an unterminated /regex/ is left for the ROLE=PROFILE parsing to reject
+	if pair != "" {
+		pairs = append(pairs, strings.TrimSpace(pair))
+	}
+
+	return pairs
+}
@@ -0,0 +1,52 @@
+package cfg
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitRoleProfile(t *testing.T) {
+	for _, tc := range []struct {
+		value, role, profile string
+		ok                   bool
+	}{
+		{"acs:ram::000000000001:role/admin=prod", "acs:ram::000000000001:role/admin", "prod", true},
+		{" dev-admin = dev ", "dev-admin", "dev", true},
+		{"/env=prod/=prod", "/env=prod/", "prod", true},
+		{"/a{1,3}/=dev", "/a{1,3}/", "dev", true},
+		{"/env=prod/", "", "", false},
+		{"acs:ram::*:role/admin", "", "", false},
+	} {
+		role, profile, ok := SplitRoleProfile(tc.value)
+		require.Equal(t, tc.ok, ok, tc.value)
+		require.Equal(t, tc.role, role, tc.value)
+		require.Equal(t, tc.profile, profile, tc.value)
+	}
+}
+
+func TestSplitRoleProfiles(t *testing.T) {
+	require.Nil(t, splitRoleProfiles(""))
+	require.Equal(t, []string{"dev-admin=dev", "prod-admin=prod"}, splitRoleProfiles("dev-admin=dev, prod-admin=prod"))
+	require.Equal(t, []string{"/a{1,3}/=dev", "/env=prod/=prod", "admin=ops"}, splitRoleProfiles("/a{1,3}/=dev,/env=prod/=prod,admin=ops"))
+	require.Equal(t, []string{"admin=ops", "/a{1,3}"}, splitRoleProfiles("admin=ops,/a{1,3}"))
+}
+
+func TestLoadRoleProfilesRegex(t *testing.T) {
+
+	err := ioutil.WriteFile(throwAwayConfig, []byte(`[work]
+url           = https://id.whatever.com
+role_profiles = /a{1,3}/=dev,prod-admin=prod
+`), 0600)
+	require.Nil(t, err)
+	defer os.Remove(throwAwayConfig)
+
+	cfgm, err := NewConfigManager(throwAwayConfig)
+	require.Nil(t, err)
+
+	account, err := cfgm.LookupIDPAccount("work")
+	require.Nil(t, err)
+	require.Equal(t, []string{"/a{1,3}/=dev", "prod-admin=prod"}, account.RoleProfiles)
+}
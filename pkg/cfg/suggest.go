@@ -0,0 +1,109 @@
+package cfg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ini "gopkg.in/ini.v1"
+)
+
+// maxSuggestions the number of idp account names offered when the selected one doesn't exist
+const maxSuggestions = 3
+
+// AccountNotFoundError returned when the selected idp account has no section in the configuration file,
+// its cause is ErrIdpAccountNotFound
+type AccountNotFoundError struct {
+	Name        string
+	Suggestions []string
+}
+
+func (e *AccountNotFoundError) Error() string {
+	msg := fmt.Sprintf("IDP account %s not found", e.Name)
+	if len(e.Suggestions) > 0 {
+		return msg + fmt.Sprintf(", did you mean %s? Run config list to see the configured accounts", strings.Join(e.Suggestions, " or "))
+	}
+	return msg + ", run configure to set it up or config list to see the configured accounts"
+}
+
+// Cause the error the lookup failed with, for errors.Cause
+func (e *AccountNotFoundError) Cause() error {
+	return ErrIdpAccountNotFound
+}
+
+func newAccountNotFoundError(cfg *ini.File, idpAccountName string) error {
+	names := []string{}
+	for _, name := range cfg.SectionStrings() {
+		if !isReservedSection(name) {
+			names = append(names, name)
+		}
+	}
+
+	return &AccountNotFoundError{Name: idpAccountName, Suggestions: suggestNames(idpAccountName, names)}
+}
+
+// suggestNames the names closest to the one supplied, a name is close when it is within a few edits of it
+// or one contains the other
+func suggestNames(name string, names []string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	lower := strings.ToLower(name)
+	maxDistance := len(name)/3 + 1
+
+	candidates := []candidate{}
+	for _, n := range names {
+		nl := strings.ToLower(n)
+		distance := levenshtein(lower, nl)
+		if distance <= maxDistance || (lower != "" && (strings.Contains(nl, lower) || strings.Contains(lower, nl))) {
+			candidates = append(candidates, candidate{n, distance})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	suggestions := []string{}
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].name)
+	}
+	return suggestions
+}
+
+// levenshtein the number of single character insertions, deletions and substitutions turning a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,53 @@
+package cfg
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestNames(t *testing.T) {
+	names := []string{"work", "work-dev", "personal", "staging"}
+
+	require.Equal(t, []string{"work"}, suggestNames("wrk", names))
+	require.Equal(t, []string{"personal"}, suggestNames("Personl", names))
+	require.Equal(t, []string{"work-dev", "work"}, suggestNames("work-de", names))
+	require.Empty(t, suggestNames("production", names))
+}
+
+func TestLookupIDPAccount(t *testing.T) {
+
+	err := ioutil.WriteFile(throwAwayConfig, []byte(`[work]
+url = https://id.whatever.com
+
+[account_aliases]
+123456789012 = prod
+`), 0600)
+	require.Nil(t, err)
+	defer os.Remove(throwAwayConfig)
+
+	cfgm, err := NewConfigManager(throwAwayConfig)
+	require.Nil(t, err)
+
+	account, err := cfgm.LookupIDPAccount("work")
+	require.Nil(t, err)
+	require.Equal(t, "https://id.whatever.com", account.URL)
+
+	_, err = cfgm.LookupIDPAccount("wrok")
+	require.Equal(t, ErrIdpAccountNotFound, errors.Cause(err))
+	require.EqualError(t, err, "IDP account wrok not found, did you mean work? Run config list to see the configured accounts")
+
+	_, err = cfgm.LookupIDPAccount(AccountAliasesSection)
+	require.Equal(t, ErrIdpAccountNotFound, errors.Cause(err))
+
+	exists, err := cfgm.IDPAccountExists("work")
+	require.Nil(t, err)
+	require.True(t, exists)
+
+	exists, err = cfgm.IDPAccountExists(AccountAliasesSection)
+	require.Nil(t, err)
+	require.False(t, exists)
+}
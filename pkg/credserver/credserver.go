@@ -0,0 +1,186 @@
+package credserver
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SecurityCredentialsPath the ECS instance metadata path listing the RAM role of the instance
+	SecurityCredentialsPath = "/latest/meta-data/ram/security-credentials/"
+
+	// TokenPath the ECS instance metadata path handing out the tokens of the hardened mode
+	TokenPath = "/latest/api/token"
+
+	// expirationFormat the format the ECS instance metadata uses for timestamps
+	expirationFormat = "2006-01-02T15:04:05Z"
+)
+
+var logger = logrus.WithField("pkg", "credserver")
+
+// Provider returns unexpired credentials, refreshing them when required
+type Provider func() (*alibabacloudconfig.AliCloudCredentials, error)
+
+// ecsCredentials the document served for the RAM role by the ECS instance metadata
+type ecsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	AccessKeySecret string `json:"AccessKeySecret"`
+	SecurityToken   string `json:"SecurityToken"`
+	Expiration      string `json:"Expiration"`
+	LastUpdated     string `json:"LastUpdated"`
+	Code            string `json:"Code"`
+}
+
+// NewECSMetadataHandler emulate the RAM role endpoints of the ECS instance metadata, the role is named
+// after the role of the credentials supplied by the provider, only requests for a loopback host are served
+func NewECSMetadataHandler(provider Provider) http.Handler {
+	mux := http.NewServeMux()
+
+	// the hardened mode only requires a token to be presented, any token is accepted
+	mux.HandleFunc(TokenPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		token, err := NewToken()
+		if err != nil {
+			logger.WithError(err).Error("unable to generate token")
+			http.Error(w, "unable to generate token", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, token)
+	})
+
+	mux.HandleFunc(SecurityCredentialsPath, func(w http.ResponseWriter, r *http.Request) {
+		creds, err := provider()
+		if err != nil {
+			logger.WithError(err).Error("unable to provide credentials")
+			http.Error(w, "unable to provide credentials", http.StatusInternalServerError)
+			return
+		}
+
+		roleName := RoleName(creds.PrincipalARN)
+		requested := strings.TrimPrefix(r.URL.Path, SecurityCredentialsPath)
+
+		if requested == "" {
+			fmt.Fprint(w, roleName)
+			return
+		}
+
+		if requested != roleName {
+			http.NotFound(w, r)
+			return
+		}
+
+		writeJSON(w, &ecsCredentials{
+			AccessKeyID:     creds.AliCloudAccessKey,
+			AccessKeySecret: creds.AliCloudSecretKey,
+			SecurityToken:   creds.AliCloudSecurityToken,
+			Expiration:      creds.Expires.UTC().Format(expirationFormat),
+			LastUpdated:     time.Now().UTC().Format(expirationFormat),
+			Code:            "Success",
+		})
+	})
+
+	return loopbackOnly(mux)
+}
+
+// NewURIHandler serve the credentials in the format read by the credentials URI provider of the SDKs,
+// the token must be presented as a bearer token or as the token query parameter since the SDKs can only
+// be configured with the URI, without a token every request is refused
+func NewURIHandler(provider Provider, token string) http.Handler {
+	return loopbackOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" || !authorized(r, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		creds, err := provider()
+		if err != nil {
+			logger.WithError(err).Error("unable to provide credentials")
+			http.Error(w, "unable to provide credentials", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, &ecsCredentials{
+			AccessKeyID:     creds.AliCloudAccessKey,
+			AccessKeySecret: creds.AliCloudSecretKey,
+			SecurityToken:   creds.AliCloudSecurityToken,
+			Expiration:      creds.Expires.UTC().Format(expirationFormat),
+			LastUpdated:     time.Now().UTC().Format(expirationFormat),
+			Code:            "Success",
+		})
+	}))
+}
+
+// loopbackOnly refuse requests for a host which isn't a loopback name or address, a web page resolving its
+// own name to 127.0.0.1 (DNS rebinding) would otherwise be able to read the credentials
+func loopbackOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLoopbackHost(r.Host) {
+			logger.WithField("host", r.Host).Warn("refused request for a non loopback host")
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isLoopbackHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = strings.Trim(hostport, "[]")
+	}
+
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func authorized(r *http.Request, token string) bool {
+	presented := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		presented = strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
+
+// RoleName the name of the role in an assumed role ARN, acs:ram::<uid>:assumed-role/<role>/<session>
+func RoleName(principalARN string) string {
+	tokens := strings.Split(principalARN, "/")
+	if len(tokens) < 2 {
+		return "saml2alibabacloud"
+	}
+	return tokens[1]
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.WithError(err).Error("unable to write response")
+	}
+}
+
+// NewToken generate a random token used to protect the credentials URI
+func NewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "error generating token")
+	}
+	return hex.EncodeToString(b), nil
+}
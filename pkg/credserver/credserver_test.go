@@ -0,0 +1,170 @@
+package credserver
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/alibabacloudconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testProvider() (*alibabacloudconfig.AliCloudCredentials, error) {
+	return &alibabacloudconfig.AliCloudCredentials{
+		AliCloudAccessKey:     "STS.testid",
+		AliCloudSecretKey:     "testsecret",
+		AliCloudSecurityToken: "testtoken",
+		PrincipalARN:          "acs:ram::123123123123:assumed-role/admin/alice",
+		Expires:               time.Date(2015, 4, 9, 11, 52, 19, 0, time.UTC),
+	}, nil
+}
+
+func TestECSMetadataHandler(t *testing.T) {
+	ts := httptest.NewServer(NewECSMetadataHandler(testProvider))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + SecurityCredentialsPath)
+	require.Nil(t, err)
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	require.Nil(t, err)
+	assert.Equal(t, "admin", string(body))
+
+	res, err = http.Get(ts.URL + SecurityCredentialsPath + "admin")
+	require.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var creds map[string]string
+	err = json.NewDecoder(res.Body).Decode(&creds)
+	require.Nil(t, err)
+	assert.Equal(t, "STS.testid", creds["AccessKeyId"])
+	assert.Equal(t, "testsecret", creds["AccessKeySecret"])
+	assert.Equal(t, "testtoken", creds["SecurityToken"])
+	assert.Equal(t, "2015-04-09T11:52:19Z", creds["Expiration"])
+	assert.Equal(t, "Success", creds["Code"])
+
+	res, err = http.Get(ts.URL + SecurityCredentialsPath + "other")
+	require.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestECSMetadataHandlerToken(t *testing.T) {
+	ts := httptest.NewServer(NewECSMetadataHandler(testProvider))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodPut, ts.URL+TokenPath, nil)
+	require.Nil(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	require.Nil(t, err)
+	assert.Len(t, body, 32)
+}
+
+func TestECSMetadataHandlerProviderError(t *testing.T) {
+	ts := httptest.NewServer(NewECSMetadataHandler(func() (*alibabacloudconfig.AliCloudCredentials, error) {
+		return nil, errors.New("login failed")
+	}))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + SecurityCredentialsPath + "admin")
+	require.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+}
+
+func TestRoleName(t *testing.T) {
+	assert.Equal(t, "admin", RoleName("acs:ram::123123123123:assumed-role/admin/alice"))
+	assert.Equal(t, "saml2alibabacloud", RoleName(""))
+}
+
+func TestURIHandler(t *testing.T) {
+	ts := httptest.NewServer(NewURIHandler(testProvider, "secret"))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "?token=secret")
+	require.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var creds map[string]string
+	err = json.NewDecoder(res.Body).Decode(&creds)
+	require.Nil(t, err)
+	assert.Equal(t, "STS.testid", creds["AccessKeyId"])
+	assert.Equal(t, "testsecret", creds["AccessKeySecret"])
+	assert.Equal(t, "testtoken", creds["SecurityToken"])
+	assert.Equal(t, "2015-04-09T11:52:19Z", creds["Expiration"])
+}
+
+func TestURIHandlerToken(t *testing.T) {
+	ts := httptest.NewServer(NewURIHandler(testProvider, "secret"))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	require.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res, err = http.Get(ts.URL + "?token=wrong")
+	require.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res, err = http.Get(ts.URL + "?token=secret")
+	require.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestURIHandlerWithoutToken(t *testing.T) {
+	ts := httptest.NewServer(NewURIHandler(testProvider, ""))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "?token=")
+	require.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestLoopbackOnly(t *testing.T) {
+	for _, handler := range []http.Handler{NewECSMetadataHandler(testProvider), NewURIHandler(testProvider, "secret")} {
+		ts := httptest.NewServer(handler)
+
+		req, err := http.NewRequest(http.MethodGet, ts.URL+SecurityCredentialsPath+"admin?token=secret", nil)
+		require.Nil(t, err)
+		req.Host = "attacker.example.com:9911"
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		ts.Close()
+	}
+}
+
+func TestIsLoopbackHost(t *testing.T) {
+	assert.True(t, isLoopbackHost("127.0.0.1:9911"))
+	assert.True(t, isLoopbackHost("localhost:9911"))
+	assert.True(t, isLoopbackHost("[::1]:9911"))
+	assert.True(t, isLoopbackHost("127.0.0.1"))
+	assert.True(t, isLoopbackHost("[::1]"))
+	assert.False(t, isLoopbackHost("attacker.example.com:9911"))
+	assert.False(t, isLoopbackHost("192.168.1.10:9911"))
+	assert.False(t, isLoopbackHost(""))
+}
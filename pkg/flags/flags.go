@@ -27,14 +27,24 @@ type CommonFlags struct {
 	ResourceID      string
 	DisableKeychain bool
 	Region          string
+	STSEndpoint     string
+	STSRegion       string
+	OfflineRoles    bool
+	RoleProfiles    []string
+	Policy          string
+	PolicyFile      string
 }
 
 // LoginExecFlags flags for the Login / Exec commands
 type LoginExecFlags struct {
-	CommonFlags  *CommonFlags
-	Force        bool
-	DuoMFAOption string
-	ExecProfile  string
+	CommonFlags     *CommonFlags
+	Force           bool
+	DuoMFAOption    string
+	ExecProfile     string
+	URI             bool
+	Refresh         bool
+	Shell           bool
+	ExternalProfile string
 }
 
 type ConsoleFlags struct {
@@ -42,6 +52,56 @@ type ConsoleFlags struct {
 	Link           bool
 }
 
+// ServeFlags flags for the Serve command
+type ServeFlags struct {
+	LoginExecFlags *LoginExecFlags
+	Address        string
+	URI            bool
+	Token          string
+}
+
+// ConfigFlags flags for the config commands
+type ConfigFlags struct {
+	CommonFlags *CommonFlags
+	Account     string
+	Key         string
+	Value       string
+	NewName     string
+}
+
+// StatusFlags flags for the Status command
+type StatusFlags struct {
+	CommonFlags *CommonFlags
+	Output      string
+	Verify      bool
+}
+
+// LogoutFlags flags for the Logout command
+type LogoutFlags struct {
+	LoginExecFlags *LoginExecFlags
+	All            bool
+	DeletePassword bool
+}
+
+// ScriptFlags flags for the Script command
+type ScriptFlags struct {
+	LoginExecFlags *LoginExecFlags
+	Shell          string
+	Unset          bool
+}
+
+// ShellFlags flags for the Shell command
+type ShellFlags struct {
+	LoginExecFlags *LoginExecFlags
+	ForceNested    bool
+}
+
+// ListRolesFlags flags for the ListRoles command
+type ListRolesFlags struct {
+	LoginExecFlags *LoginExecFlags
+	Output         string
+}
+
 // ApplyFlagOverrides overrides IDPAccount with command line settings
 func ApplyFlagOverrides(commonFlags *CommonFlags, account *cfg.IDPAccount) {
 	if commonFlags.AppID != "" {
@@ -93,4 +153,22 @@ func ApplyFlagOverrides(commonFlags *CommonFlags, account *cfg.IDPAccount) {
 	if commonFlags.Region != "" {
 		account.Region = commonFlags.Region
 	}
+	if commonFlags.STSEndpoint != "" {
+		account.STSEndpoint = commonFlags.STSEndpoint
+	}
+	if commonFlags.STSRegion != "" {
+		account.STSRegion = commonFlags.STSRegion
+	}
+	if commonFlags.OfflineRoles {
+		account.OfflineRoles = commonFlags.OfflineRoles
+	}
+	if len(commonFlags.RoleProfiles) > 0 {
+		account.RoleProfiles = commonFlags.RoleProfiles
+	}
+	if commonFlags.PolicyFile != "" {
+		account.SessionPolicy = commonFlags.PolicyFile
+	}
+	if commonFlags.Policy != "" {
+		account.SessionPolicy = commonFlags.Policy
+	}
 }
@@ -18,6 +18,11 @@ func TestOverrideAllFlags(t *testing.T) {
 		AlibabaCloudURN: "urn:alibaba:cloudcomputing",
 		SessionDuration: 3600,
 		Profile:         "saml",
+		STSEndpoint:     "http://localhost:8080",
+		STSRegion:       "ap-southeast-1",
+		OfflineRoles:    true,
+		RoleProfiles:    []string{"prod-admin=prod", "dev-admin=dev"},
+		PolicyFile:      "policy.json",
 	}
 	idpa := &cfg.IDPAccount{
 		Provider:        "Ping",
@@ -37,6 +42,11 @@ func TestOverrideAllFlags(t *testing.T) {
 		AlibabaCloudURN: "urn:alibaba:cloudcomputing",
 		SessionDuration: 3600,
 		Profile:         "saml",
+		STSEndpoint:     "http://localhost:8080",
+		STSRegion:       "ap-southeast-1",
+		OfflineRoles:    true,
+		RoleProfiles:    []string{"prod-admin=prod", "dev-admin=dev"},
+		SessionPolicy:   "policy.json",
 	}
 	ApplyFlagOverrides(commonFlags, idpa)
 
@@ -3,10 +3,15 @@ package prompter
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	survey "github.com/AlecAivazis/survey/v2"
 )
 
+// selectPageSize the number of options shown at once when choosing with a default, typing filters the rest
+const selectPageSize = 15
+
 // CliPrompter used to prompt for cli input
 type CliPrompter struct {
 }
@@ -30,9 +35,11 @@ func (cli *CliPrompter) RequestSecurityCode(pattern string) string {
 func (cli *CliPrompter) ChooseWithDefault(pr string, defaultValue string, options []string) (string, error) {
 	selected := ""
 	prompt := &survey.Select{
-		Message: pr,
-		Options: options,
-		Default: defaultValue,
+		Message:  pr,
+		Options:  options,
+		Default:  defaultValue,
+		PageSize: selectPageSize,
+		Filter:   FuzzyMatch,
 	}
 	survey.AskOne(prompt, &selected, survey.WithValidator(survey.Required))
 
@@ -93,3 +100,22 @@ func (cli *CliPrompter) Password(pr string) string {
 	survey.AskOne(prompt, &val)
 	return val
 }
+
+// FuzzyMatch returns true when every whitespace separated term of the filter appears in the value
+// in order, though not necessarily contiguously, ignoring case. "prd adm" matches "production / admin".
+func FuzzyMatch(filter string, value string, index int) bool {
+	value = strings.ToLower(value)
+
+	for _, term := range strings.Fields(strings.ToLower(filter)) {
+		pos := 0
+		for _, r := range term {
+			i := strings.IndexRune(value[pos:], r)
+			if i < 0 {
+				return false
+			}
+			pos += i + utf8.RuneLen(r)
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,19 @@
+package prompter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	value := "production(000000000002) / Admin"
+
+	assert.True(t, FuzzyMatch("", value, 0))
+	assert.True(t, FuzzyMatch("prod", value, 0))
+	assert.True(t, FuzzyMatch("prd adm", value, 0))
+	assert.True(t, FuzzyMatch("PRD ADM", value, 0))
+	assert.True(t, FuzzyMatch("0002 admin", value, 0))
+	assert.False(t, FuzzyMatch("dev", value, 0))
+	assert.False(t, FuzzyMatch("admin prod x", value, 0))
+}
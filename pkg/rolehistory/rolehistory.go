@@ -0,0 +1,95 @@
+package rolehistory
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultPath the default location of the file remembering the roles recently chosen
+	DefaultPath = "~/.saml2alibabacloud.roles.json"
+
+	// DefaultSize the number of roles remembered for each idp account
+	DefaultSize = 5
+)
+
+// History the roles recently chosen for each idp account, most recent first
+type History struct {
+	path  string
+	Roles map[string][]string `json:"roles"`
+}
+
+// Load read the history from the supplied path, a missing file results in an empty history
+func Load(path string) (*History, error) {
+	if path == "" {
+		path = DefaultPath
+	}
+
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &History{path: path, Roles: map[string][]string{}}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading role history")
+	}
+
+	if err := json.Unmarshal(data, history); err != nil {
+		return nil, errors.Wrap(err, "error parsing role history")
+	}
+	if history.Roles == nil {
+		history.Roles = map[string][]string{}
+	}
+
+	return history, nil
+}
+
+// Recent the role ARNs recently chosen for the idp account, most recent first
+func (h *History) Recent(idpAccount string) []string {
+	return h.Roles[idpAccount]
+}
+
+// Add record the role ARN as the most recent choice for the idp account, keeping at most size entries
+func (h *History) Add(idpAccount string, roleARN string, size int) {
+	roles := []string{roleARN}
+	for _, role := range h.Roles[idpAccount] {
+		if role != roleARN {
+			roles = append(roles, role)
+		}
+	}
+
+	if len(roles) > size {
+		roles = roles[:size]
+	}
+
+	h.Roles[idpAccount] = roles
+}
+
+// Save write the history back to the file it was loaded from
+func (h *History) Save() error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return errors.Wrap(err, "error creating role history directory")
+	}
+
+	if err := ioutil.WriteFile(h.path, data, 0600); err != nil {
+		return errors.Wrap(err, "error writing role history")
+	}
+
+	return nil
+}
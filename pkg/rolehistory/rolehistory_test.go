@@ -0,0 +1,37 @@
+package rolehistory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rolehistory")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "roles.json")
+
+	history, err := Load(path)
+	require.Nil(t, err)
+	assert.Empty(t, history.Recent("default"))
+
+	history.Add("default", "acs:ram::000000000001:role/admin", 2)
+	history.Add("default", "acs:ram::000000000002:role/admin", 2)
+	history.Add("default", "acs:ram::000000000001:role/admin", 2)
+	history.Add("default", "acs:ram::000000000003:role/admin", 2)
+	history.Add("other", "acs:ram::000000000004:role/admin", 2)
+
+	err = history.Save()
+	require.Nil(t, err)
+
+	history, err = Load(path)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"acs:ram::000000000003:role/admin", "acs:ram::000000000001:role/admin"}, history.Recent("default"))
+	assert.Equal(t, []string{"acs:ram::000000000004:role/admin"}, history.Recent("other"))
+}
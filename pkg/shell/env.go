@@ -24,3 +24,53 @@ func BuildEnvVars(alibabacloudCreds *alibabacloudconfig.AliCloudCredentials, acc
 	}
 	return environmentVars
 }
+
+// ScriptVar an env var exported by the script command
+type ScriptVar struct {
+	Name  string
+	Value string
+}
+
+// BuildScriptVars build the env vars exported by the script command, in the order they are emitted
+func BuildScriptVars(alibabacloudCreds *alibabacloudconfig.AliCloudCredentials, profile string) []ScriptVar {
+	return []ScriptVar{
+		{"ALIBABA_CLOUD_ACCESS_KEY_ID", alibabacloudCreds.AliCloudAccessKey},
+		{"ALIBABA_CLOUD_ACCESS_KEY_SECRET", alibabacloudCreds.AliCloudSecretKey},
+		{"ALIBABA_CLOUD_SESSION_TOKEN", alibabacloudCreds.AliCloudSessionToken},
+		{"ALIBABA_CLOUD_SECURITY_TOKEN", alibabacloudCreds.AliCloudSecurityToken},
+		{"ALICLOUD_ACCESS_KEY", alibabacloudCreds.AliCloudAccessKey},
+		{"ALICLOUD_SECRET_KEY", alibabacloudCreds.AliCloudSecretKey},
+		{"ALICLOUD_SECURITY_TOKEN", alibabacloudCreds.AliCloudSecurityToken},
+		{"ALICLOUD_PROFILE", profile},
+		{"SAML2ALIBABA_CLOUD_PROFILE", profile},
+	}
+}
+
+// FormatEnvVars format the vars in the NAME=value format required for exec
+func FormatEnvVars(vars []ScriptVar) []string {
+	environmentVars := make([]string, len(vars))
+	for i, v := range vars {
+		environmentVars[i] = fmt.Sprintf("%s=%s", v.Name, v.Value)
+	}
+	return environmentVars
+}
+
+// StaticCredentialsEnvVars the env vars holding static keys, the SDKs prefer them over a credentials URI so
+// they are removed from the environment of a command reading the URI, e.g. when run inside saml2alibabacloud shell
+var StaticCredentialsEnvVars = []string{
+	"ALIBABA_CLOUD_ACCESS_KEY_ID",
+	"ALIBABA_CLOUD_ACCESS_KEY_SECRET",
+	"ALIBABA_CLOUD_SECURITY_TOKEN",
+	"ALIBABA_CLOUD_SESSION_TOKEN",
+	"ALICLOUD_ACCESS_KEY",
+	"ALICLOUD_SECRET_KEY",
+	"ALICLOUD_SECURITY_TOKEN",
+	"ALICLOUD_ASSUME_ROLE_SESSION_NAME",
+}
+
+// BuildCredentialsURIEnvVars build the env vars pointing the SDKs at a credentials URI instead of static keys
+func BuildCredentialsURIEnvVars(uri string) []string {
+	return []string{
+		fmt.Sprintf("ALIBABA_CLOUD_CREDENTIALS_URI=%s", uri),
+	}
+}
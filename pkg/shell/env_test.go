@@ -56,3 +56,28 @@ func TestBuildEnvVars(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatEnvVars(t *testing.T) {
+	alibabacloudCreds := &alibabacloudconfig.AliCloudCredentials{
+		AliCloudAccessKey:     "123",
+		AliCloudSecretKey:     "345",
+		AliCloudSecurityToken: "567",
+		AliCloudSessionToken:  "789",
+	}
+
+	want := []string{
+		"ALIBABA_CLOUD_ACCESS_KEY_ID=123",
+		"ALIBABA_CLOUD_ACCESS_KEY_SECRET=345",
+		"ALIBABA_CLOUD_SESSION_TOKEN=789",
+		"ALIBABA_CLOUD_SECURITY_TOKEN=567",
+		"ALICLOUD_ACCESS_KEY=123",
+		"ALICLOUD_SECRET_KEY=345",
+		"ALICLOUD_SECURITY_TOKEN=567",
+		"ALICLOUD_PROFILE=saml",
+		"SAML2ALIBABA_CLOUD_PROFILE=saml",
+	}
+
+	if got := FormatEnvVars(BuildScriptVars(alibabacloudCreds, "saml")); !reflect.DeepEqual(got, want) {
+		t.Errorf("FormatEnvVars() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// ExitError returned when the command exits with a non zero code
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", e.Code)
+}
+
+// ExecCmd exec the command directly, without a shell, so the arguments reach it exactly as supplied,
+// the unset env vars of this process aren't passed on to the command
+func ExecCmd(argv []string, envVars []string, unset ...string) error {
+	return supervise(newCmd(argv, envVars, unset))
+}
+
+// ExecShellCmd exec shell command using the default shell, the unset env vars of this process aren't passed
+// on to the command
+func ExecShellCmd(cmdline []string, envVars []string, unset ...string) error {
+	return supervise(shellCmd(cmdline, envVars, unset))
+}
+
+func newCmd(argv []string, envVars []string, unset []string) *exec.Cmd {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(withoutEnvVars(os.Environ(), unset), envVars...)
+
+	return cmd
+}
+
+// withoutEnvVars drop the named vars from the NAME=value environment
+func withoutEnvVars(environ []string, names []string) []string {
+	if len(names) == 0 {
+		return environ
+	}
+
+	kept := []string{}
+	for _, kv := range environ {
+		name := strings.SplitN(kv, "=", 2)[0]
+
+		drop := false
+		for _, unset := range names {
+			if name == unset {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, kv)
+		}
+	}
+	return kept
+}
+
+// supervise run the command until it exits, forwarding SIGINT and SIGTERM to it,
+// a non zero exit is returned as an ExitError carrying the code of the command
+func supervise(cmd *exec.Cmd) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				_ = cmd.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return &ExitError{Code: exitCode(exitErr)}
+	}
+	return err
+}
@@ -3,22 +3,22 @@
 package shell
 
 import (
-	"os"
 	"os/exec"
 	"strings"
+	"syscall"
 )
 
-// ExecShellCmd exec shell command using the default shell
-func ExecShellCmd(cmdline []string, envVars []string) error {
+func shellCmd(cmdline []string, envVars []string, unset []string) *exec.Cmd {
 
 	c := strings.Join(cmdline, " ")
 
-	cs := []string{"/bin/sh", "-c", c}
-	cmd := exec.Command(cs[0], cs[1:]...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Env = append(os.Environ(), envVars...)
+	return newCmd([]string{"/bin/sh", "-c", c}, envVars, unset)
+}
 
-	return cmd.Run()
+// exitCode the exit code of the command, a command killed by a signal exits with 128 plus the signal like in a shell
+func exitCode(exitErr *exec.ExitError) int {
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return exitErr.ExitCode()
 }
@@ -2,6 +2,7 @@
 
 package shell
 
+import "os"
 import "testing"
 import "github.com/stretchr/testify/assert"
 
@@ -12,3 +13,40 @@ func TestExecShellCmd(t *testing.T) {
 	assert.Nil(t, err)
 
 }
+
+func TestExecShellCmdExitCode(t *testing.T) {
+
+	err := ExecShellCmd([]string{"exit", "$TESTTEST"}, []string{"TESTTEST=3"})
+
+	assert.Equal(t, &ExitError{Code: 3}, err)
+
+	err = ExecShellCmd([]string{"kill", "-TERM", "$$"}, nil)
+
+	assert.Equal(t, &ExitError{Code: 143}, err)
+}
+
+func TestExecCmd(t *testing.T) {
+
+	// the arguments are not split or expanded by a shell
+	err := ExecCmd([]string{"/bin/sh", "-c", `test "$1" = 'a b $TESTTEST'`, "sh", "a b $TESTTEST"}, []string{"TESTTEST=123"})
+
+	assert.Nil(t, err)
+
+	err = ExecCmd([]string{"/bin/sh", "-c", "exit 7"}, nil)
+
+	assert.Equal(t, &ExitError{Code: 7}, err)
+}
+
+func TestExecCmdUnset(t *testing.T) {
+
+	os.Setenv("ALICLOUD_ACCESS_KEY", "STS.static")
+	defer os.Unsetenv("ALICLOUD_ACCESS_KEY")
+
+	err := ExecCmd([]string{"/bin/sh", "-c", `test -z "${ALICLOUD_ACCESS_KEY+set}" && test "$TESTTEST" = 123`}, []string{"TESTTEST=123"}, StaticCredentialsEnvVars...)
+
+	assert.Nil(t, err)
+
+	err = ExecShellCmd([]string{"test", "-z", `"${ALICLOUD_ACCESS_KEY+set}"`}, nil, "ALICLOUD_ACCESS_KEY")
+
+	assert.Nil(t, err)
+}
@@ -12,6 +12,30 @@ type RamRole struct {
 	Name         string
 }
 
+// AccountID the id of the account owning the role, taken from the role ARN
+func (r *RamRole) AccountID() string {
+	// acs:ram::<uid>:role/<name>
+	tokens := strings.SplitN(r.RoleARN, ":", 5)
+	if len(tokens) != 5 {
+		return ""
+	}
+
+	return tokens[3]
+}
+
+// RoleName the name of the role, taken from the role ARN when it wasn't supplied by the role selection page
+func (r *RamRole) RoleName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	if i := strings.LastIndex(r.RoleARN, "role/"); i >= 0 {
+		return r.RoleARN[i+len("role/"):]
+	}
+
+	return ""
+}
+
 // ParseRamRoles parses and splits the roles while also validating the contents
 func ParseRamRoles(roles []string) ([]*RamRole, error) {
 	ramRoles := make([]*RamRole, len(roles))
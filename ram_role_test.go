@@ -30,3 +30,16 @@ func TestParseRoles(t *testing.T) {
 	assert.Nil(t, ramRoles)
 
 }
+
+func TestRamRoleAccountIDAndName(t *testing.T) {
+	ramRole := &RamRole{RoleARN: "acs:ram::456456456456:role/admin"}
+	assert.Equal(t, "456456456456", ramRole.AccountID())
+	assert.Equal(t, "admin", ramRole.RoleName())
+
+	ramRole.Name = "Admin"
+	assert.Equal(t, "Admin", ramRole.RoleName())
+
+	ramRole = &RamRole{RoleARN: "invalid"}
+	assert.Equal(t, "", ramRole.AccountID())
+	assert.Equal(t, "", ramRole.RoleName())
+}
@@ -0,0 +1,227 @@
+package saml2alibabacloud
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"hash"
+	"io/ioutil"
+
+	"github.com/beevik/etree"
+	"github.com/pkg/errors"
+)
+
+const (
+	encryptedAssertionTag = "EncryptedAssertion"
+	encryptedDataTag      = "EncryptedData"
+	encryptedKeyTag       = "EncryptedKey"
+	encryptionMethodTag   = "EncryptionMethod"
+	digestMethodTag       = "DigestMethod"
+	cipherValueTag        = "CipherValue"
+
+	aes128CBC = "http://www.w3.org/2001/04/xmlenc#aes128-cbc"
+	aes192CBC = "http://www.w3.org/2001/04/xmlenc#aes192-cbc"
+	aes256CBC = "http://www.w3.org/2001/04/xmlenc#aes256-cbc"
+	aes128GCM = "http://www.w3.org/2009/xmlenc11#aes128-gcm"
+	aes192GCM = "http://www.w3.org/2009/xmlenc11#aes192-gcm"
+	aes256GCM = "http://www.w3.org/2009/xmlenc11#aes256-gcm"
+
+	rsaOAEPMGF1P = "http://www.w3.org/2001/04/xmlenc#rsa-oaep-mgf1p"
+	rsaOAEP      = "http://www.w3.org/2009/xmlenc11#rsa-oaep"
+	rsaV15       = "http://www.w3.org/2001/04/xmlenc#rsa-1_5"
+
+	digestSHA1   = "http://www.w3.org/2000/09/xmldsig#sha1"
+	digestSHA256 = "http://www.w3.org/2001/04/xmlenc#sha256"
+	digestSHA512 = "http://www.w3.org/2001/04/xmlenc#sha512"
+)
+
+// ErrEncryptedAssertion indicates that the assertion is encrypted and can't be read without the SP private key
+var ErrEncryptedAssertion = errors.New("SAML assertion is encrypted, an SP private key is required to read it")
+
+// LoadSPPrivateKey load the SP private key used to decrypt assertions from a PEM file
+func LoadSPPrivateKey(filename string) (*rsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading SP private key file")
+	}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			rsaKey, ok := key.(*rsa.PrivateKey)
+			if !ok {
+				return nil, errors.New("SP private key is not an RSA key")
+			}
+			return rsaKey, nil
+		}
+	}
+
+	return nil, errors.Errorf("no private key found in %s", filename)
+}
+
+// DecryptAssertion replace the EncryptedAssertion in the SAML response with the decrypted Assertion so the
+// extract functions can read it, a response without an EncryptedAssertion is returned unchanged
+func DecryptAssertion(data []byte, key *rsa.PrivateKey) ([]byte, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, err
+	}
+
+	encryptedAssertionElement := doc.FindElement(".//" + encryptedAssertionTag)
+	if encryptedAssertionElement == nil {
+		return data, nil
+	}
+
+	if key == nil {
+		return nil, ErrEncryptedAssertion
+	}
+
+	encryptedDataElement := encryptedAssertionElement.FindElement(childPath("", encryptedDataTag))
+	if encryptedDataElement == nil {
+		return nil, ErrMissingElement{Tag: encryptedDataTag}
+	}
+
+	// the EncryptedKey is either carried in the KeyInfo of the EncryptedData or alongside it
+	encryptedKeyElement := encryptedAssertionElement.FindElement(".//" + encryptedKeyTag)
+	if encryptedKeyElement == nil {
+		return nil, ErrMissingElement{Tag: encryptedKeyTag}
+	}
+
+	sessionKey, err := decryptKey(encryptedKeyElement, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "error decrypting assertion key")
+	}
+
+	plaintext, err := decryptData(encryptedDataElement, sessionKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "error decrypting assertion")
+	}
+
+	assertionDoc := etree.NewDocument()
+	if err := assertionDoc.ReadFromBytes(plaintext); err != nil {
+		return nil, errors.Wrap(err, "error parsing decrypted assertion")
+	}
+
+	assertionElement := assertionDoc.Root()
+	if assertionElement == nil || assertionElement.Tag != assertionTag {
+		return nil, ErrMissingAssertion
+	}
+
+	parent := encryptedAssertionElement.Parent()
+	parent.InsertChildAt(encryptedAssertionElement.Index(), assertionElement)
+	parent.RemoveChild(encryptedAssertionElement)
+
+	return doc.WriteToBytes()
+}
+
+func decryptKey(encryptedKeyElement *etree.Element, key *rsa.PrivateKey) ([]byte, error) {
+	encryptionMethod := encryptedKeyElement.FindElement(childPath("", encryptionMethodTag))
+	if encryptionMethod == nil {
+		return nil, ErrMissingElement{Tag: encryptionMethodTag}
+	}
+
+	ciphertext, err := cipherValue(encryptedKeyElement)
+	if err != nil {
+		return nil, err
+	}
+
+	switch algorithm := encryptionMethod.SelectAttrValue("Algorithm", ""); algorithm {
+	case rsaOAEPMGF1P, rsaOAEP:
+		h := sha1.New()
+		if digestMethod := encryptionMethod.FindElement(childPath("", digestMethodTag)); digestMethod != nil {
+			if h, err = digestHash(digestMethod.SelectAttrValue("Algorithm", "")); err != nil {
+				return nil, err
+			}
+		}
+		return rsa.DecryptOAEP(h, nil, key, ciphertext, nil)
+	case rsaV15:
+		return rsa.DecryptPKCS1v15(nil, key, ciphertext)
+	default:
+		return nil, errors.Errorf("unsupported key transport algorithm: %s", algorithm)
+	}
+}
+
+func decryptData(encryptedDataElement *etree.Element, sessionKey []byte) ([]byte, error) {
+	encryptionMethod := encryptedDataElement.FindElement(childPath("", encryptionMethodTag))
+	if encryptionMethod == nil {
+		return nil, ErrMissingElement{Tag: encryptionMethodTag}
+	}
+
+	ciphertext, err := cipherValue(encryptedDataElement)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch algorithm := encryptionMethod.SelectAttrValue("Algorithm", ""); algorithm {
+	case aes128CBC, aes192CBC, aes256CBC:
+		if len(ciphertext) < 2*aes.BlockSize || len(ciphertext)%aes.BlockSize != 0 {
+			return nil, errors.New("invalid ciphertext length")
+		}
+
+		plaintext := make([]byte, len(ciphertext)-aes.BlockSize)
+		cipher.NewCBCDecrypter(block, ciphertext[:aes.BlockSize]).CryptBlocks(plaintext, ciphertext[aes.BlockSize:])
+
+		// XML encryption padding, the last byte holds the number of padding bytes
+		padding := int(plaintext[len(plaintext)-1])
+		if padding == 0 || padding > aes.BlockSize {
+			return nil, errors.New("invalid padding")
+		}
+		return plaintext[:len(plaintext)-padding], nil
+	case aes128GCM, aes192GCM, aes256GCM:
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		if len(ciphertext) < gcm.NonceSize() {
+			return nil, errors.New("invalid ciphertext length")
+		}
+		return gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], nil)
+	default:
+		return nil, errors.Errorf("unsupported block encryption algorithm: %s", algorithm)
+	}
+}
+
+func cipherValue(el *etree.Element) ([]byte, error) {
+	cipherValueElement := el.FindElement(childPath("", "CipherData") + "/" + cipherValueTag)
+	if cipherValueElement == nil {
+		return nil, ErrMissingElement{Tag: cipherValueTag}
+	}
+
+	return base64.StdEncoding.DecodeString(stripWhitespace(cipherValueElement.Text()))
+}
+
+func digestHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case digestSHA1:
+		return sha1.New(), nil
+	case digestSHA256:
+		return sha256.New(), nil
+	case digestSHA512:
+		return sha512.New(), nil
+	default:
+		return nil, errors.Errorf("unsupported digest algorithm: %s", algorithm)
+	}
+}
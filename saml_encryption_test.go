@@ -0,0 +1,75 @@
+package saml2alibabacloud
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecryptAssertion(t *testing.T) {
+	key, err := LoadSPPrivateKey("testdata/sp_key.pem")
+	require.Nil(t, err)
+
+	for _, fixture := range []string{"testdata/assertion_encrypted.xml", "testdata/assertion_encrypted_gcm.xml"} {
+		data, err := ioutil.ReadFile(fixture)
+		require.Nil(t, err)
+
+		_, err = ExtractRamRoles(data)
+		assert.Equal(t, ErrMissingAssertion, err, fixture)
+
+		decrypted, err := DecryptAssertion(data, key)
+		require.Nil(t, err, fixture)
+
+		roles, err := ExtractRamRoles(decrypted)
+		assert.Nil(t, err, fixture)
+		assert.Len(t, roles, 2, fixture)
+
+		duration, err := ExtractSessionDuration(decrypted)
+		assert.Nil(t, err, fixture)
+		assert.Equal(t, int64(28800), duration, fixture)
+	}
+}
+
+func TestDecryptAssertionNotEncrypted(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/assertion.xml")
+	require.Nil(t, err)
+
+	decrypted, err := DecryptAssertion(data, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, data, decrypted)
+}
+
+func TestDecryptAssertionMissingKey(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/assertion_encrypted.xml")
+	require.Nil(t, err)
+
+	_, err = DecryptAssertion(data, nil)
+	assert.Equal(t, ErrEncryptedAssertion, err)
+}
+
+func TestVerifySignatureEncryptedAssertion(t *testing.T) {
+	certs, err := LoadIDPCertificates(&cfg.IDPAccount{IDPCertificate: "testdata/idp_cert.pem"})
+	require.Nil(t, err)
+
+	key, err := LoadSPPrivateKey("testdata/sp_key.pem")
+	require.Nil(t, err)
+
+	data, err := ioutil.ReadFile("testdata/assertion_encrypted.xml")
+	require.Nil(t, err)
+
+	_, err = VerifySignature(data, certs)
+	assert.Equal(t, ErrEncryptedAssertion, err)
+
+	decrypted, err := DecryptAssertion(data, key)
+	require.Nil(t, err)
+
+	verified, err := VerifySignature(decrypted, certs)
+	require.Nil(t, err)
+
+	roles, err := ExtractRamRoles(verified)
+	require.Nil(t, err)
+	assert.NotEmpty(t, roles)
+}
@@ -0,0 +1,259 @@
+package saml2alibabacloud
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/aliyun/saml2alibabacloud/pkg/provider"
+	"github.com/beevik/etree"
+	"github.com/pkg/errors"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+)
+
+const (
+	signatureTag       = "Signature"
+	keyDescriptorTag   = "KeyDescriptor"
+	x509CertificateTag = "X509Certificate"
+
+	// defaultMetadataTimeout how long fetching the IdP metadata may take when the idp account has no timeout
+	defaultMetadataTimeout = 30 * time.Second
+)
+
+var (
+	// ErrMissingSignature indicates that neither the Response nor the Assertion carry an enveloped signature
+	ErrMissingSignature = errors.New("SAML response is not signed")
+
+	// ErrNoIDPCertificates indicates that signature verification was requested without any certificate to verify against
+	ErrNoIDPCertificates = errors.New("no IdP certificates available to verify the SAML response signature")
+)
+
+// SignatureVerificationEnabled returns true if the idp account is configured with a source of IdP certificates
+func SignatureVerificationEnabled(idpAccount *cfg.IDPAccount) bool {
+	return idpAccount.IDPCertificate != "" || idpAccount.IDPMetadataURL != ""
+}
+
+// LoadIDPCertificates load the IdP signing certificates from the PEM file and/or metadata URL configured on the idp account
+func LoadIDPCertificates(idpAccount *cfg.IDPAccount) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+
+	if idpAccount.IDPCertificate != "" {
+		data, err := ioutil.ReadFile(idpAccount.IDPCertificate)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading IdP certificate file")
+		}
+
+		pemCerts, err := ParsePEMCertificates(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing IdP certificate file %s", idpAccount.IDPCertificate)
+		}
+		certs = append(certs, pemCerts...)
+	}
+
+	if idpAccount.IDPMetadataURL != "" {
+		res, err := getMetadata(idpAccount)
+		if err != nil {
+			return nil, errors.Wrap(err, "error retrieving IdP metadata")
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return nil, errors.Errorf("error retrieving IdP metadata: %s", res.Status)
+		}
+
+		data, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return nil, errors.Wrap(err, "error retrieving IdP metadata body")
+		}
+
+		metadataCerts, err := ExtractMetadataCertificates(data)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing IdP metadata")
+		}
+		certs = append(certs, metadataCerts...)
+	}
+
+	if len(certs) == 0 {
+		return nil, ErrNoIDPCertificates
+	}
+
+	return certs, nil
+}
+
+// getMetadata fetch the IdP metadata with the HTTP settings of the idp account, the certificates are trust
+// anchors so only https is accepted
+func getMetadata(idpAccount *cfg.IDPAccount) (*http.Response, error) {
+	metadataURL, err := url.Parse(idpAccount.IDPMetadataURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing IdP metadata URL")
+	}
+	if metadataURL.Scheme != "https" {
+		return nil, errors.Errorf("IdP metadata URL %s must use https", idpAccount.IDPMetadataURL)
+	}
+
+	client, err := provider.NewHTTPClient(provider.NewDefaultTransport(idpAccount.SkipVerify), provider.BuildHttpClientOpts(idpAccount))
+	if err != nil {
+		return nil, errors.Wrap(err, "error building http client")
+	}
+
+	client.Timeout = defaultMetadataTimeout
+	if idpAccount.Timeout > 0 {
+		client.Timeout = time.Duration(idpAccount.Timeout) * time.Second
+	}
+
+	req, err := http.NewRequest(http.MethodGet, metadataURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Do(req)
+}
+
+// ParsePEMCertificates parse all the CERTIFICATE blocks in the supplied PEM data
+func ParsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found in PEM data")
+	}
+
+	return certs, nil
+}
+
+// ExtractMetadataCertificates extract the signing certificates from an IdP metadata document
+func ExtractMetadataCertificates(data []byte) ([]*x509.Certificate, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, err
+	}
+
+	certs := []*x509.Certificate{}
+
+	for _, keyDescriptor := range doc.FindElements(".//" + keyDescriptorTag) {
+		// a KeyDescriptor without a use attribute applies to both signing and encryption
+		if use := keyDescriptor.SelectAttrValue("use", "signing"); use != "signing" {
+			continue
+		}
+
+		for _, certElement := range keyDescriptor.FindElements(".//" + x509CertificateTag) {
+			certData, err := base64.StdEncoding.DecodeString(stripWhitespace(certElement.Text()))
+			if err != nil {
+				return nil, errors.Wrap(err, "error decoding metadata certificate")
+			}
+
+			cert, err := x509.ParseCertificate(certData)
+			if err != nil {
+				return nil, errors.Wrap(err, "error parsing metadata certificate")
+			}
+			certs = append(certs, cert)
+		}
+	}
+
+	if len(certs) == 0 {
+		return nil, ErrMissingElement{Tag: keyDescriptorTag}
+	}
+
+	return certs, nil
+}
+
+// VerifySignature verify the enveloped signature on the SAML Response, or on its Assertion
+// if the Response itself is not signed, against the supplied IdP certificates. The validated
+// element is returned so the roles and attributes are only ever read from signed content. When
+// only an encrypted assertion is present ErrEncryptedAssertion is returned, the decrypted response
+// should then be verified again.
+func VerifySignature(data []byte, certs []*x509.Certificate) ([]byte, error) {
+	if len(certs) == 0 {
+		return nil, ErrNoIDPCertificates
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, err
+	}
+
+	responseElement := doc.Root()
+	if responseElement == nil || responseElement.Tag != responseTag {
+		return nil, ErrMissingElement{Tag: responseTag}
+	}
+
+	// only a single assertion is accepted, otherwise an unsigned assertion could be
+	// wrapped alongside a signed one
+	assertionElements := doc.FindElements(".//" + assertionTag)
+	encryptedAssertionElements := doc.FindElements(".//" + encryptedAssertionTag)
+	if count := len(assertionElements) + len(encryptedAssertionElements); count == 0 {
+		return nil, ErrMissingAssertion
+	} else if count > 1 {
+		return nil, errors.Errorf("expected a single %s element, found %d", assertionTag, count)
+	}
+
+	ctx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: certs})
+
+	if responseElement.FindElement(childPath("", signatureTag)) != nil {
+		validated, err := ctx.Validate(responseElement)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid signature on SAML Response")
+		}
+		return serializeElement(validated)
+	}
+
+	// the signature of an encrypted assertion can only be checked once it has been decrypted
+	if len(encryptedAssertionElements) > 0 {
+		return nil, ErrEncryptedAssertion
+	}
+
+	assertionElement := assertionElements[0]
+	if assertionElement.FindElement(childPath("", signatureTag)) == nil {
+		return nil, ErrMissingSignature
+	}
+
+	// detach the assertion carrying the namespaces declared on the Response
+	nsCtx, err := etreeutils.NSBuildParentContext(assertionElement)
+	if err != nil {
+		return nil, err
+	}
+	detached, err := etreeutils.NSDetatch(nsCtx, assertionElement)
+	if err != nil {
+		return nil, err
+	}
+
+	validated, err := ctx.Validate(detached)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid signature on SAML Assertion")
+	}
+
+	return serializeElement(validated)
+}
+
+func serializeElement(el *etree.Element) ([]byte, error) {
+	doc := etree.NewDocument()
+	doc.SetRoot(el.Copy())
+	return doc.WriteToBytes()
+}
+
+func stripWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
@@ -0,0 +1,154 @@
+package saml2alibabacloud
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aliyun/saml2alibabacloud/pkg/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifySignatureAssertion(t *testing.T) {
+	certs, err := LoadIDPCertificates(&cfg.IDPAccount{IDPCertificate: "testdata/idp_cert.pem"})
+	require.Nil(t, err)
+
+	data, err := ioutil.ReadFile("testdata/assertion_signed.xml")
+	require.Nil(t, err)
+
+	verified, err := VerifySignature(data, certs)
+	require.Nil(t, err)
+
+	roles, err := ExtractRamRoles(verified)
+	require.Nil(t, err)
+	assert.NotEmpty(t, roles)
+}
+
+func TestVerifySignatureResponse(t *testing.T) {
+	certs, err := LoadIDPCertificates(&cfg.IDPAccount{IDPCertificate: "testdata/idp_cert.pem"})
+	require.Nil(t, err)
+
+	data, err := ioutil.ReadFile("testdata/response_signed.xml")
+	require.Nil(t, err)
+
+	verified, err := VerifySignature(data, certs)
+	require.Nil(t, err)
+
+	roles, err := ExtractRamRoles(verified)
+	require.Nil(t, err)
+	assert.NotEmpty(t, roles)
+}
+
+func TestVerifySignatureTampered(t *testing.T) {
+	certs, err := LoadIDPCertificates(&cfg.IDPAccount{IDPCertificate: "testdata/idp_cert.pem"})
+	require.Nil(t, err)
+
+	for _, fixture := range []string{"testdata/assertion_signed.xml", "testdata/response_signed.xml"} {
+		data, err := ioutil.ReadFile(fixture)
+		require.Nil(t, err)
+
+		tampered := strings.Replace(string(data), "Ali-CloudAdminOps-Build", "Ali-CloudAdminOps-Prod", 1)
+
+		_, err = VerifySignature([]byte(tampered), certs)
+		assert.Error(t, err, fixture)
+	}
+}
+
+func TestVerifySignatureUnsigned(t *testing.T) {
+	certs, err := LoadIDPCertificates(&cfg.IDPAccount{IDPCertificate: "testdata/idp_cert.pem"})
+	require.Nil(t, err)
+
+	data, err := ioutil.ReadFile("testdata/assertion_unsigned.xml")
+	require.Nil(t, err)
+
+	_, err = VerifySignature(data, certs)
+	assert.Equal(t, ErrMissingSignature, err)
+}
+
+func TestVerifySignatureWrappedAssertion(t *testing.T) {
+	certs, err := LoadIDPCertificates(&cfg.IDPAccount{IDPCertificate: "testdata/idp_cert.pem"})
+	require.Nil(t, err)
+
+	data, err := ioutil.ReadFile("testdata/assertion_signed.xml")
+	require.Nil(t, err)
+
+	unsigned, err := ioutil.ReadFile("testdata/assertion_unsigned.xml")
+	require.Nil(t, err)
+
+	// inject the unsigned assertion ahead of the signed one
+	start := strings.Index(string(unsigned), "<Assertion")
+	end := strings.Index(string(unsigned), "</Assertion>") + len("</Assertion>")
+	wrapped := strings.Replace(string(data), "<Assertion", string(unsigned[start:end])+"\n  <Assertion", 1)
+
+	_, err = VerifySignature([]byte(wrapped), certs)
+	assert.Error(t, err)
+}
+
+func TestVerifySignatureNoCertificates(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/assertion_signed.xml")
+	require.Nil(t, err)
+
+	_, err = VerifySignature(data, nil)
+	assert.Equal(t, ErrNoIDPCertificates, err)
+}
+
+func TestVerifySignatureUntrustedCertificate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+
+	data, err := ioutil.ReadFile("testdata/assertion_signed.xml")
+	require.Nil(t, err)
+
+	_, err = VerifySignature(data, []*x509.Certificate{cert})
+	assert.Error(t, err)
+}
+
+func TestLoadIDPCertificatesMetadataURL(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/idp_metadata.xml")
+	}))
+	defer ts.Close()
+
+	_, err := LoadIDPCertificates(&cfg.IDPAccount{IDPMetadataURL: ts.URL})
+	assert.Error(t, err, "the certificate of the test server isn't trusted")
+
+	certs, err := LoadIDPCertificates(&cfg.IDPAccount{IDPMetadataURL: ts.URL, SkipVerify: true, Timeout: 5})
+	require.Nil(t, err)
+	assert.Len(t, certs, 1)
+
+	data, err := ioutil.ReadFile("testdata/assertion_signed.xml")
+	require.Nil(t, err)
+
+	_, err = VerifySignature(data, certs)
+	assert.Nil(t, err)
+}
+
+func TestLoadIDPCertificatesMetadataURLPlainHTTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/idp_metadata.xml")
+	}))
+	defer ts.Close()
+
+	_, err := LoadIDPCertificates(&cfg.IDPAccount{IDPMetadataURL: ts.URL})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "IdP metadata URL "+ts.URL+" must use https")
+}